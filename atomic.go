@@ -0,0 +1,158 @@
+package times
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotSynced is matched by errors.Is for the *SyncError returned when a file was
+// written and renamed into place, but its directory could not be synced.
+var ErrNotSynced = errors.New("written but not synced")
+
+// syncDirFunc syncs the directory of a replaced file, tests replace it.
+var syncDirFunc = syncDir
+
+// SyncError is returned by WriteFileAtomic, WriteIfUnchanged, Index.Save and
+// Manifest.Save when the new file is in place, but syncing its directory failed:
+// the new file may be lost in a crash. Unlike other errors, the write did happen.
+type SyncError struct {
+	Dir string
+	Err error
+}
+
+func (e *SyncError) Error() string {
+	return "sync " + e.Dir + ": " + e.Err.Error() + " (written but not synced)"
+}
+
+// Unwrap returns e.Err.
+func (e *SyncError) Unwrap() error { return e.Err }
+
+// Is makes errors.Is(err, ErrNotSynced) true.
+func (e *SyncError) Is(target error) bool {
+	return target == ErrNotSynced
+}
+
+// WriteOptions configures WriteFileAtomic.
+type WriteOptions struct {
+	// Perm is used if the file does not exist yet (before umask), zero means 0666.
+	// An existing file keeps its permissions.
+	Perm os.FileMode
+
+	// Keep are the times of the existing file to keep, FieldAtime, FieldMtime or both.
+	// The change and birth times of a new file can't be set and are ignored.
+	Keep Field
+
+	// Times are set on the new file, they win over Keep for every time they don't Omit.
+	Times Change
+}
+
+// WriteResult holds the times of the file before and after WriteFileAtomic.
+type WriteResult struct {
+	// Old holds the times of the replaced file, it is nil if there was no file.
+	Old Timespec
+
+	// New holds the times of the new file, read with StatFile after its times were
+	// set but before it was renamed into place (which updates the change time).
+	New Timespec
+}
+
+// WriteFileAtomic writes data to a temporary file in the same directory as name,
+// sets its times as asked for by opts and renames it over name, so readers see
+// either the old or the new contents. The times of both files are returned so
+// callers can audit what changed, also with a *SyncError.
+func WriteFileAtomic(name string, data []byte, opts WriteOptions) (WriteResult, error) {
+	var r WriteResult
+
+	old, err := Stat(name)
+	switch {
+	case err == nil:
+		r.Old = old
+	case !os.IsNotExist(err):
+		return r, err
+	}
+
+	err = replaceFile(name, data, opts.Perm, func(f *os.File) (err error) {
+		// the times must be set after the last write.
+		c := keepTimes(opts.Times, opts.Keep, r.Old)
+		if c == (Change{}) {
+			r.New, err = StatFile(f)
+		} else {
+			r.New, err = FileChtimes(f, c)
+		}
+		return err
+	})
+	return r, err
+}
+
+// replaceFile writes data to a temporary file in the same directory as name and
+// renames it over name. The temporary file gets the permissions of name if it
+// exists, perm (or 0666) otherwise. prepare is called with the written temporary
+// file right before it is closed and renamed, an error from it aborts the replace.
+// The directory is synced after the rename, so the new file survives a crash,
+// a failure to sync it is a *SyncError.
+func replaceFile(name string, data []byte, perm os.FileMode, prepare func(f *os.File) error) (err error) {
+	if perm == 0 {
+		perm = 0666
+	}
+	fi, err := os.Stat(name)
+	switch {
+	case err == nil:
+		perm = fi.Mode().Perm()
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	renamed := false
+	defer func() {
+		if err != nil && !renamed {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if err = prepare(f); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, name); err != nil {
+		return err
+	}
+	renamed = true
+
+	dir := filepath.Dir(name)
+	if err := syncDirFunc(dir); err != nil {
+		return &SyncError{Dir: dir, Err: err}
+	}
+	return nil
+}
+
+// keepTimes returns c with the times in keep taken from old, unless c sets them.
+func keepTimes(c Change, keep Field, old Timespec) Change {
+	if old == nil {
+		return c
+	}
+	if keep&FieldAtime != 0 && c.Atime.mode == updateOmit {
+		c.Atime = At(old.AccessTime())
+	}
+	if keep&FieldMtime != 0 && c.Mtime.mode == updateOmit {
+		c.Mtime = At(old.ModTime())
+	}
+	return c
+}
@@ -0,0 +1,160 @@
+package times
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomicNew(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "new")
+
+	r, err := WriteFileAtomic(name, []byte("new"), WriteOptions{Perm: 0600, Keep: FieldAtime | FieldMtime})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r.Old != nil {
+		t.Errorf("got old times %v for a new file", r.Old)
+	}
+	timespecTest(r.New, newInterval(time.Now(), time.Second), t)
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(data) != "new" {
+		t.Errorf("got contents %q", data)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("got mode %s, want %s", fi.Mode().Perm(), os.FileMode(0600))
+		}
+	}
+}
+
+func TestWriteFileAtomicKeep(t *testing.T) {
+	tests := []struct {
+		name      string
+		keep      Field
+		wantAtime bool
+		wantMtime bool
+	}{
+		{name: "none"},
+		{name: "atime", keep: FieldAtime, wantAtime: true},
+		{name: "mtime", keep: FieldMtime, wantMtime: true},
+		{name: "both", keep: FieldAtime | FieldMtime, wantAtime: true, wantMtime: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldFileTest(t, func(dir, name string, old time.Time) {
+				r, err := WriteFileAtomic(name, []byte("replaced"), WriteOptions{Keep: test.keep})
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				timespecTest(r.Old, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+				ts, err := Stat(name)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				checkKept := func(get timeFetcher, kept bool) {
+					want := newInterval(time.Now(), time.Second)
+					if kept {
+						want = newInterval(old, 0)
+					}
+					timespecTest(r.New, want, t, get)
+					timespecTest(ts, want, t, get)
+				}
+				checkKept(Timespec.AccessTime, test.wantAtime)
+				checkKept(Timespec.ModTime, test.wantMtime)
+
+				data, err := ioutil.ReadFile(name)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				if string(data) != "replaced" {
+					t.Errorf("got contents %q", data)
+				}
+			})
+		})
+	}
+}
+
+func TestWriteFileAtomicTimes(t *testing.T) {
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		older := old.Add(-time.Hour)
+		r, err := WriteFileAtomic(name, []byte("replaced"), WriteOptions{
+			Keep:  FieldAtime | FieldMtime,
+			Times: Change{Mtime: At(older)},
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(r.New, newInterval(old, 0), t, Timespec.AccessTime)
+		timespecTest(r.New, newInterval(older, 0), t, Timespec.ModTime)
+	})
+}
+
+func TestWriteFileAtomicPerm(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("windows does not have unix permissions")
+	}
+
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		if _, err := WriteFileAtomic(name, []byte("replaced"), WriteOptions{Perm: 0600}); err != nil {
+			t.Fatal(err.Error())
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if fi.Mode().Perm() != 0640 {
+			t.Errorf("got mode %s, want the original %s", fi.Mode().Perm(), os.FileMode(0640))
+		}
+	})
+}
+
+func TestWriteFileAtomicErr(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := WriteFileAtomic(filepath.Join(dir, "missing", "file"), nil, WriteOptions{}); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+
+	// renaming a file over a dir fails, the temp file is cleaned up.
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "dir", "child"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteFileAtomic(filepath.Join(dir, "dir"), nil, WriteOptions{}); err == nil {
+		t.Error("expected an error replacing a dir")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the temp file to be removed, got %d entries", len(entries))
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := syncDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		return
+	}
+	if err := syncDir(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+}
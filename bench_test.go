@@ -3,6 +3,7 @@ package times
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func BenchmarkGet(t *testing.B) {
@@ -63,3 +64,78 @@ func BenchmarkOsLstat(t *testing.B) {
 	})
 	t.ReportAllocs()
 }
+
+func BenchmarkStatInto(t *testing.B) {
+	fileTest(t, func(f *os.File) {
+		var ts Times
+		for i := 0; i < t.N; i++ {
+			StatInto(f.Name(), &ts)
+		}
+	})
+	t.ReportAllocs()
+}
+
+func BenchmarkLstatInto(t *testing.B) {
+	fileTest(t, func(f *os.File) {
+		var ts Times
+		for i := 0; i < t.N; i++ {
+			LstatInto(f.Name(), &ts)
+		}
+	})
+	t.ReportAllocs()
+}
+
+func BenchmarkStatFileInto(t *testing.B) {
+	fileTest(t, func(f *os.File) {
+		var ts Times
+		for i := 0; i < t.N; i++ {
+			StatFileInto(f, &ts)
+		}
+	})
+	t.ReportAllocs()
+}
+
+func BenchmarkStatParallel(t *testing.B) {
+	fileTest(t, func(f *os.File) {
+		t.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				Stat(f.Name())
+			}
+		})
+	})
+	t.ReportAllocs()
+}
+
+func BenchmarkCacheStat(t *testing.B) {
+	fileTest(t, func(f *os.File) {
+		c := NewCache(CacheOptions{TTL: time.Hour})
+		for i := 0; i < t.N; i++ {
+			c.Stat(f.Name())
+		}
+	})
+	t.ReportAllocs()
+}
+
+func BenchmarkCacheStatInto(t *testing.B) {
+	fileTest(t, func(f *os.File) {
+		c := NewCache(CacheOptions{TTL: time.Hour})
+		var ts Times
+		for i := 0; i < t.N; i++ {
+			c.StatInto(f.Name(), &ts)
+		}
+	})
+	t.ReportAllocs()
+}
+
+func BenchmarkCacheStatParallel(t *testing.B) {
+	fileTest(t, func(f *os.File) {
+		c := NewCache(CacheOptions{TTL: time.Hour})
+		t.RunParallel(func(pb *testing.PB) {
+			var ts Times
+			for pb.Next() {
+				c.StatInto(f.Name(), &ts)
+			}
+		})
+	})
+	t.ReportAllocs()
+}
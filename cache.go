@@ -0,0 +1,220 @@
+package times
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures a Cache.
+type CacheOptions struct {
+	// TTL is how long the times of a path are cached, zero means 1s.
+	TTL time.Duration
+
+	// Size is the maximum number of cached paths, the least recently used
+	// one is evicted to make room. Zero means 1024.
+	Size int
+
+	// Statter gets the times, nil means DefaultStatter.
+	Statter *Statter
+}
+
+// CacheStats are the counters of a Cache.
+type CacheStats struct {
+	// Hits is the number of lookups answered from the cache.
+	Hits uint64
+
+	// Misses is the number of lookups which waited for a Stat, including the
+	// ones which shared the Stat of a concurrent lookup of the same path.
+	Misses uint64
+
+	// Stats is the number of Stat and Lstat calls made.
+	Stats uint64
+}
+
+// Cache caches the times of paths for a while, to save the syscalls of looking up
+// the same paths over and over. Concurrent lookups of a path which is not cached
+// share a single Stat. Errors are not cached.
+//
+// A Cache is safe for concurrent use.
+type Cache struct {
+	ttl     time.Duration
+	size    int
+	statter *Statter
+	now     func() time.Time
+
+	mu       sync.Mutex
+	lru      *list.List // of *cacheEntry, most recently used first
+	entries  map[cacheKey]*list.Element
+	inflight map[cacheKey]*cacheCall
+	stats    CacheStats
+}
+
+type cacheKey struct {
+	name     string
+	nofollow bool
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	t       Times
+	expires time.Time
+}
+
+// cacheCall is a Stat shared by concurrent lookups.
+type cacheCall struct {
+	wg  sync.WaitGroup
+	t   Times
+	err error
+}
+
+// NewCache returns a Cache configured by opts.
+func NewCache(opts CacheOptions) *Cache {
+	c := &Cache{
+		ttl:      opts.TTL,
+		size:     opts.Size,
+		statter:  opts.Statter,
+		now:      time.Now,
+		lru:      list.New(),
+		entries:  make(map[cacheKey]*list.Element),
+		inflight: make(map[cacheKey]*cacheCall),
+	}
+	if c.ttl <= 0 {
+		c.ttl = time.Second
+	}
+	if c.size <= 0 {
+		c.size = 1024
+	}
+	if c.statter == nil {
+		c.statter = DefaultStatter
+	}
+	return c
+}
+
+// Stat returns the Timespec for the given filename, see Cache.
+func (c *Cache) Stat(name string) (Timespec, error) {
+	var t Times
+	if err := c.StatInto(name, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// StatInto fills dst with the times for the given filename, see Cache.
+func (c *Cache) StatInto(name string, dst *Times) error {
+	return c.lookup(cacheKey{name: name}, dst)
+}
+
+// Lstat returns the Timespec for the given filename, and does not follow Symlinks, see Cache.
+func (c *Cache) Lstat(name string) (Timespec, error) {
+	var t Times
+	if err := c.LstatInto(name, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LstatInto fills dst with the times for the given filename, and does not follow Symlinks, see Cache.
+func (c *Cache) LstatInto(name string, dst *Times) error {
+	return c.lookup(cacheKey{name: name, nofollow: true}, dst)
+}
+
+// Invalidate forgets the cached times of name, for both Stat and Lstat.
+// A lookup of name which is in progress is not shared with later ones.
+func (c *Cache) Invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, nofollow := range []bool{false, true} {
+		key := cacheKey{name: name, nofollow: nofollow}
+		if e, ok := c.entries[key]; ok {
+			c.remove(e)
+		}
+		delete(c.inflight, key)
+	}
+}
+
+// Purge forgets every cached time.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[cacheKey]*list.Element)
+	c.inflight = make(map[cacheKey]*cacheCall)
+}
+
+// Len returns the number of cached paths, some may have expired.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the counters of c.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *Cache) lookup(key cacheKey, dst *Times) error {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*cacheEntry)
+		if c.now().Before(entry.expires) {
+			c.lru.MoveToFront(e)
+			*dst = entry.t
+			c.stats.Hits++
+			c.mu.Unlock()
+			return nil
+		}
+		c.remove(e)
+	}
+	c.stats.Misses++
+
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		call.wg.Wait()
+		*dst = call.t
+		return call.err
+	}
+	call := &cacheCall{}
+	call.wg.Add(1)
+	c.inflight[key] = call
+	c.stats.Stats++
+	c.mu.Unlock()
+
+	if key.nofollow {
+		call.err = c.statter.LstatInto(key.name, &call.t)
+	} else {
+		call.err = c.statter.StatInto(key.name, &call.t)
+	}
+	call.wg.Done()
+
+	c.mu.Lock()
+	// an Invalidate or Purge while the Stat was in progress may make its result stale.
+	if c.inflight[key] == call {
+		delete(c.inflight, key)
+		if call.err == nil {
+			c.add(key, call.t)
+		}
+	}
+	c.mu.Unlock()
+
+	*dst = call.t
+	return call.err
+}
+
+func (c *Cache) add(key cacheKey, t Times) {
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, t: t, expires: c.now().Add(c.ttl)})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).key)
+}
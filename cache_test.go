@@ -0,0 +1,211 @@
+package times
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newCacheTest(opts CacheOptions) (*Cache, *fakeClock) {
+	c := NewCache(opts)
+	clock := &fakeClock{t: time.Now()}
+	c.now = clock.now
+	return c, clock
+}
+
+func expectCacheStats(t *testing.T, c *Cache, want CacheStats) {
+	t.Helper()
+	if got := c.Stats(); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+}
+
+func TestCache(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		c, clock := newCacheTest(CacheOptions{TTL: time.Minute})
+
+		want, err := Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		for i := 0; i < 3; i++ {
+			ts, err := c.Stat(f.Name())
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !ts.(Times).Equal(want.(Times)) {
+				t.Errorf("got %v, want %v", ts, want)
+			}
+		}
+		expectCacheStats(t, c, CacheStats{Hits: 2, Misses: 1, Stats: 1})
+
+		// Lstat is cached apart.
+		if _, err := c.Lstat(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+		expectCacheStats(t, c, CacheStats{Hits: 2, Misses: 2, Stats: 2})
+
+		// cached times are returned until they expire.
+		at := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(f.Name(), at, at); err != nil {
+			t.Fatal(err.Error())
+		}
+		clock.t = clock.t.Add(time.Minute - 1)
+		ts, err := c.Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(want.ModTime(), 0), t, Timespec.ModTime)
+
+		clock.t = clock.t.Add(1)
+		ts, err = c.Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(at, 0), t, Timespec.ModTime)
+		expectCacheStats(t, c, CacheStats{Hits: 3, Misses: 3, Stats: 3})
+	})
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		c, _ := newCacheTest(CacheOptions{TTL: time.Hour})
+		if _, err := c.Stat(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, err := c.Lstat(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		at := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(f.Name(), at, at); err != nil {
+			t.Fatal(err.Error())
+		}
+		c.Invalidate(f.Name())
+		if c.Len() != 0 {
+			t.Errorf("got %d cached paths, want 0", c.Len())
+		}
+		ts, err := c.Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(at, 0), t, Timespec.ModTime)
+
+		c.Purge()
+		if c.Len() != 0 {
+			t.Errorf("got %d cached paths after Purge, want 0", c.Len())
+		}
+		expectCacheStats(t, c, CacheStats{Misses: 3, Stats: 3})
+	})
+}
+
+func TestCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := newCacheTest(CacheOptions{Size: 2})
+	stat := func(name string) {
+		t.Helper()
+		if _, err := c.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	stat("a")
+	stat("b")
+	stat("a") // b is the least recently used now.
+	stat("c")
+	if c.Len() != 2 {
+		t.Errorf("got %d cached paths, want 2", c.Len())
+	}
+	expectCacheStats(t, c, CacheStats{Hits: 1, Misses: 3, Stats: 3})
+
+	stat("a")
+	expectCacheStats(t, c, CacheStats{Hits: 2, Misses: 3, Stats: 3})
+	stat("b")
+	expectCacheStats(t, c, CacheStats{Hits: 2, Misses: 4, Stats: 4})
+}
+
+func TestCacheErr(t *testing.T) {
+	c := NewCache(CacheOptions{})
+	name := filepath.Join(t.TempDir(), "missing")
+	for i := 0; i < 2; i++ {
+		if _, err := c.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("got err %v, want not exist", err)
+		}
+	}
+	expectCacheStats(t, c, CacheStats{Misses: 2, Stats: 2})
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	c := NewCache(CacheOptions{})
+	name := "shared"
+	want := Times{Mtime: time.Now()}
+
+	// a Stat in progress.
+	call := &cacheCall{}
+	call.wg.Add(1)
+	c.inflight[cacheKey{name: name}] = call
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var ts Times
+			if err := c.StatInto(name, &ts); err != nil {
+				t.Error(err.Error())
+			}
+			if !ts.Equal(want) {
+				t.Errorf("got %v, want %v", ts, want)
+			}
+		}()
+	}
+	for c.Stats().Misses != 10 {
+		time.Sleep(time.Millisecond)
+	}
+	call.t = want
+	call.wg.Done()
+	wg.Wait()
+	expectCacheStats(t, c, CacheStats{Misses: 10})
+}
+
+func TestCacheInvalidateInflight(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		c := NewCache(CacheOptions{TTL: time.Hour})
+		key := cacheKey{name: f.Name()}
+		call := &cacheCall{}
+		call.wg.Add(1)
+		c.inflight[key] = call
+
+		c.Invalidate(f.Name())
+		if _, err := c.Stat(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+		expectCacheStats(t, c, CacheStats{Misses: 1, Stats: 1})
+	})
+}
+
+func TestCacheStatIntoAllocs(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		c := NewCache(CacheOptions{TTL: time.Hour})
+		var ts Times
+		if err := c.StatInto(f.Name(), &ts); err != nil {
+			t.Fatal(err.Error())
+		}
+		if n := testing.AllocsPerRun(100, func() { c.StatInto(f.Name(), &ts) }); n != 0 {
+			t.Errorf("got %v allocs for a cache hit, want 0", n)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package times
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrConflict is matched by errors.Is for the *ConflictError returned by WriteIfUnchanged.
+var ErrConflict = errors.New("file changed")
+
+// ConflictError is returned by WriteIfUnchanged when the file is not the one
+// the caller read anymore.
+type ConflictError struct {
+	Name string
+
+	// Expected are the times the caller read, Actual are the times of the file
+	// now, it is nil if the file does not exist.
+	Expected, Actual Timespec
+
+	// Changed are the times which differ.
+	Changed Field
+
+	// Replaced is true if name is another file (device and inode) now, or if it
+	// was created or removed.
+	Replaced bool
+}
+
+func (e *ConflictError) Error() string {
+	switch {
+	case e.Actual == nil:
+		return e.Name + ": file changed: removed"
+	case e.Expected == nil:
+		return e.Name + ": file changed: created"
+	case e.Replaced:
+		return e.Name + ": file changed: replaced"
+	}
+	return e.Name + ": file changed: " + e.Changed.String()
+}
+
+// Is makes errors.Is(err, ErrConflict) true.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// WriteIfUnchanged replaces the contents of name with data, like WriteFileAtomic,
+// but only if the file is still the one expected was read from: its modification
+// time, its change time (where available) and its device and inode numbers (where
+// known, e.g. from Stat on linux) must be the same, to the nanosecond. A nil expected
+// means name must not exist. Otherwise it returns a *ConflictError and leaves the
+// file alone.
+//
+// The file is checked before the temporary file is written, and once more right
+// before it is renamed over name. This narrows the window for a lost update a lot,
+// but it does not close it: two processes checking in the same instant can still
+// both succeed, the filesystem has no compare-and-swap rename.
+//
+// The times of the new file are returned, read after the rename (which changes the
+// change time), so they can be used as expected for the next write. They are returned
+// with a *SyncError too, since the file was written.
+func WriteIfUnchanged(name string, expected Timespec, data []byte) (Timespec, error) {
+	if err := checkUnchanged(name, expected); err != nil {
+		return nil, err
+	}
+	err := replaceFile(name, data, 0, func(*os.File) error {
+		return checkUnchanged(name, expected)
+	})
+	if err != nil && !errors.Is(err, ErrNotSynced) {
+		return nil, err
+	}
+	ts, serr := Stat(name)
+	if serr != nil {
+		return nil, serr
+	}
+	return ts, err
+}
+
+// checkUnchanged returns a *ConflictError if name is not the file expected was read from.
+func checkUnchanged(name string, expected Timespec) error {
+	actual, err := Stat(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if actual == nil && expected == nil {
+		return nil
+	}
+
+	conflict := &ConflictError{Name: name, Expected: expected, Actual: actual}
+	if actual == nil || expected == nil {
+		conflict.Replaced = true
+		return conflict
+	}
+	if !actual.ModTime().Equal(expected.ModTime()) {
+		conflict.Changed |= FieldMtime
+	}
+	actualCtime, ok := ChangeTimeOK(actual)
+	if expectedCtime, eok := ChangeTimeOK(expected); ok && eok && !actualCtime.Equal(expectedCtime) {
+		conflict.Changed |= FieldCtime
+	}
+	actualID, expectedID := idOf(actual), idOf(expected)
+	if actualID != (fileID{}) && expectedID != (fileID{}) && actualID != expectedID {
+		conflict.Replaced = true
+	}
+	if conflict.Changed == 0 && !conflict.Replaced {
+		return nil
+	}
+	return conflict
+}
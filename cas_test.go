@@ -0,0 +1,169 @@
+package times
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteIfUnchanged(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "shared.json")
+
+	ts, err := WriteIfUnchanged(name, nil, []byte("1"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ts, err = WriteIfUnchanged(name, ts, []byte("2"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// someone else writes in between.
+	stale := ts
+	if _, err := WriteIfUnchanged(name, ts, []byte("3")); err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = WriteIfUnchanged(name, stale, []byte("4"))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("got err %v, want a *ConflictError", err)
+	}
+	if conflict.Name != name || conflict.Actual == nil || conflict.Expected != stale {
+		t.Errorf("unexpected conflict %+v", conflict)
+	}
+	if idOf(stale) != (fileID{}) && !conflict.Replaced {
+		t.Error("expected the renamed file to be reported as replaced")
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(data) != "3" {
+		t.Errorf("got contents %q, want the winner's", data)
+	}
+	expectNoTempFiles(t, filepath.Dir(name), 1)
+}
+
+func TestWriteIfUnchangedCreate(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		_, err := WriteIfUnchanged(f.Name(), nil, []byte("new"))
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || conflict.Expected != nil || conflict.Actual == nil || !conflict.Replaced {
+			t.Errorf("got err %v, want a created conflict", err)
+		}
+	})
+}
+
+func TestWriteIfUnchangedRemoved(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	ts, err := WriteIfUnchanged(name, nil, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = WriteIfUnchanged(name, ts, []byte("new"))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Actual != nil || !conflict.Replaced {
+		t.Errorf("got err %v, want a removed conflict", err)
+	}
+	expectNoTempFiles(t, dir, 0)
+}
+
+func TestWriteIfUnchangedTimes(t *testing.T) {
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		ts, err := Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// rewritten in place, with its old modification time restored.
+		if err := ioutil.WriteFile(name, []byte("sneaky"), 0640); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Chtimes(name, old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		_, err = WriteIfUnchanged(name, ts, []byte("new"))
+		if !ts.HasChangeTime() {
+			// nothing tells the files apart.
+			if err != nil {
+				t.Errorf("got err %v, want nil", err)
+			}
+			return
+		}
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || conflict.Changed != FieldCtime || conflict.Replaced {
+			t.Fatalf("got err %v, want a ctime conflict", err)
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if string(data) != "sneaky" {
+			t.Errorf("got contents %q", data)
+		}
+	})
+}
+
+func TestConflictErrorString(t *testing.T) {
+	ts := Times{}
+	tests := []struct {
+		err  ConflictError
+		want string
+	}{
+		{ConflictError{Name: "f", Expected: ts}, "f: file changed: removed"},
+		{ConflictError{Name: "f", Actual: ts}, "f: file changed: created"},
+		{ConflictError{Name: "f", Expected: ts, Actual: ts, Replaced: true}, "f: file changed: replaced"},
+		{ConflictError{Name: "f", Expected: ts, Actual: ts, Changed: FieldMtime | FieldCtime}, "f: file changed: mtime|ctime"},
+	}
+	for _, test := range tests {
+		if got := test.err.Error(); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
+
+func expectNoTempFiles(t *testing.T, dir string, want int) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(entries) != want {
+		t.Errorf("got %d entries in %s, want %d", len(entries), dir, want)
+	}
+}
+
+func TestWriteIfUnchangedNotSynced(t *testing.T) {
+	syncErr := errors.New("sync failed")
+	syncDirFunc = func(string) error { return syncErr }
+	defer func() { syncDirFunc = syncDir }()
+
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	ts, err := WriteIfUnchanged(name, nil, []byte("a"))
+	if !errors.Is(err, ErrNotSynced) || !errors.Is(err, syncErr) {
+		t.Fatalf("got err %v, want %v", err, ErrNotSynced)
+	}
+	if ts == nil {
+		t.Fatal("expected the times of the written file")
+	}
+	if data, err := os.ReadFile(name); err != nil || string(data) != "a" {
+		t.Fatalf("got %q, %v, want the written file", data, err)
+	}
+	expectNoTempFiles(t, dir, 1)
+
+	// the returned times are those of the file in place.
+	if _, err := WriteIfUnchanged(name, ts, []byte("b")); errors.Is(err, ErrConflict) {
+		t.Errorf("got err %v, want the write to go on", err)
+	}
+}
@@ -0,0 +1,90 @@
+package times
+
+import (
+	"os"
+	"time"
+)
+
+type updateMode uint8
+
+const (
+	updateOmit updateMode = iota
+	updateNow
+	updateSet
+)
+
+// Update describes how Chtimes changes a single file time.
+// The zero Update is Omit.
+type Update struct {
+	mode updateMode
+	t    time.Time
+}
+
+var (
+	// Omit leaves a time unchanged.
+	Omit = Update{mode: updateOmit}
+
+	// Now sets a time to the current time. On linux the kernel reads the clock,
+	// elsewhere time.Now() is used.
+	Now = Update{mode: updateNow}
+)
+
+// At sets a time to t. A zero t leaves the time unchanged, like Omit, as os.Chtimes
+// does: it is what Timespec returns for a time it does not have.
+func At(t time.Time) Update {
+	if t.IsZero() {
+		return Omit
+	}
+	return Update{mode: updateSet, t: t}
+}
+
+// resolve returns the time u sets, current is used for Omit.
+func (u Update) resolve(current time.Time) time.Time {
+	switch u.mode {
+	case updateNow:
+		return time.Now()
+	case updateSet:
+		return u.t
+	}
+	return current
+}
+
+// Change is the set of updates applied by Chtimes.
+// Change and birth times can't be set, the kernel updates the change time
+// whenever a Change is applied.
+type Change struct {
+	Atime Update
+	Mtime Update
+}
+
+// Chtimes changes the access and modification times of the named file,
+// following symlinks, and returns its times read back with Stat,
+// which shows the precision the filesystem kept.
+// Unlike os.Chtimes, either time can be left unchanged with Omit,
+// or set to the current time with Now.
+func Chtimes(name string, c Change) (Timespec, error) {
+	if err := chtimes(name, c); err != nil {
+		return nil, err
+	}
+	return Stat(name)
+}
+
+// Lchtimes is like Chtimes but does not follow symlinks, it changes the times of
+// a symlink itself and reads them back with Lstat.
+// Only linux can change the times of a symlink, elsewhere Lchtimes returns
+// ErrUnsupported for symlinks.
+func Lchtimes(name string, c Change) (Timespec, error) {
+	if err := lchtimes(name, c); err != nil {
+		return nil, err
+	}
+	return Lstat(name)
+}
+
+// FileChtimes is like Chtimes for an open *os.File and reads the times back with StatFile.
+// On linux it uses the file descriptor, so it works even if the file was renamed or removed.
+func FileChtimes(file *os.File, c Change) (Timespec, error) {
+	if err := fileChtimes(file, c); err != nil {
+		return nil, err
+	}
+	return StatFile(file)
+}
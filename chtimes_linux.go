@@ -0,0 +1,64 @@
+package times
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func (u Update) timespec() (unix.Timespec, error) {
+	switch u.mode {
+	case updateNow:
+		return unix.Timespec{Nsec: unix.UTIME_NOW}, nil
+	case updateSet:
+		return unix.TimeToTimespec(u.t)
+	}
+	return unix.Timespec{Nsec: unix.UTIME_OMIT}, nil
+}
+
+func (c Change) timespecs() (ts [2]unix.Timespec, err error) {
+	if ts[0], err = c.Atime.timespec(); err != nil {
+		return ts, err
+	}
+	ts[1], err = c.Mtime.timespec()
+	return ts, err
+}
+
+func chtimes(name string, c Change) error {
+	ts, err := c.timespecs()
+	if err != nil {
+		return pathErr("utimensat", name, err)
+	}
+	return pathErr("utimensat", name, unix.UtimesNanoAt(unix.AT_FDCWD, name, ts[:], 0))
+}
+
+func lchtimes(name string, c Change) error {
+	ts, err := c.timespecs()
+	if err != nil {
+		return pathErr("utimensat", name, err)
+	}
+	return pathErr("utimensat", name, unix.UtimesNanoAt(unix.AT_FDCWD, name, ts[:], unix.AT_SYMLINK_NOFOLLOW))
+}
+
+func fileChtimes(file *os.File, c Change) error {
+	ts, err := c.timespecs()
+	if err != nil {
+		return pathErr("futimens", file.Name(), err)
+	}
+
+	sc, err := file.SyscallConn()
+	if err != nil {
+		return pathErr("futimens", file.Name(), err)
+	}
+
+	var errno unix.Errno
+	err = sc.Control(func(fd uintptr) {
+		// futimens(fd, ts) is utimensat(fd, NULL, ts, 0).
+		_, _, errno = unix.Syscall6(unix.SYS_UTIMENSAT, fd, 0, uintptr(unsafe.Pointer(&ts[0])), 0, 0, 0)
+	})
+	if err == nil && errno != 0 {
+		err = errno
+	}
+	return pathErr("futimens", file.Name(), err)
+}
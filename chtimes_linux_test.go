@@ -0,0 +1,36 @@
+package times
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileChtimesRemoved(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		if err := os.Remove(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		old := time.Now().Add(-time.Hour)
+		ts, err := FileChtimes(f, Change{Mtime: At(old)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.ModTime)
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.AccessTime, Timespec.ChangeTime)
+	})
+}
+
+func TestChtimesNanoseconds(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		want := time.Unix(1500000000, 123456789)
+		ts, err := Chtimes(f.Name(), Change{Atime: At(want), Mtime: At(want)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !ts.AccessTime().Equal(want) || !ts.ModTime().Equal(want) {
+			t.Errorf("got atime %v and mtime %v, want %v", ts.AccessTime(), ts.ModTime(), want)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+// +build !linux
+
+package times
+
+import "os"
+
+func chtimes(name string, c Change) error {
+	return setTimes(name, c, func(cur *Times) error { return StatInto(name, cur) })
+}
+
+func lchtimes(name string, c Change) error {
+	fi, err := os.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return &os.PathError{Op: "lchtimes", Path: name, Err: ErrUnsupported}
+	}
+	return chtimes(name, c)
+}
+
+func fileChtimes(file *os.File, c Change) error {
+	return setTimes(file.Name(), c, func(cur *Times) error { return StatFileInto(file, cur) })
+}
+
+// setTimes applies c with os.Chtimes, current is used to read the times c omits.
+func setTimes(name string, c Change, current func(*Times) error) error {
+	atimeOmitted, mtimeOmitted := c.Atime.mode == updateOmit, c.Mtime.mode == updateOmit
+	if atimeOmitted && mtimeOmitted {
+		return nil
+	}
+
+	var cur Times
+	if atimeOmitted || mtimeOmitted {
+		if err := current(&cur); err != nil {
+			return err
+		}
+	}
+	return os.Chtimes(name, c.Atime.resolve(cur.Atime), c.Mtime.resolve(cur.Mtime))
+}
@@ -0,0 +1,145 @@
+package times
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestChtimes(t *testing.T) {
+	fileAndDirTest(t, func(name string) {
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		ts, err := Chtimes(name, Change{Atime: At(old), Mtime: At(old)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+		// omit atime, mtime to now.
+		ts, err = Chtimes(name, Change{Mtime: Now})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime)
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.ModTime)
+
+		// omit mtime, atime to old.
+		older := old.Add(-time.Hour)
+		ts, err = Chtimes(name, Change{Atime: At(older), Mtime: Omit})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(older, 0), t, Timespec.AccessTime)
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.ModTime)
+	})
+}
+
+func TestChtimesOmitAll(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(f.Name(), old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		ts, err := Chtimes(f.Name(), Change{})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+	})
+}
+
+func TestChtimesZero(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(f.Name(), old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// a zero time is omitted, as os.Chtimes does.
+		older := old.Add(-time.Hour)
+		ts, err := Chtimes(f.Name(), Change{Atime: At(time.Time{}), Mtime: At(older)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime)
+		timespecTest(ts, newInterval(older, 0), t, Timespec.ModTime)
+	})
+}
+
+func TestFileChtimes(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		ts, err := FileChtimes(f, Change{Atime: At(old), Mtime: At(old)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+		ts, err = FileChtimes(f, Change{Atime: Now})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.AccessTime)
+		timespecTest(ts, newInterval(old, 0), t, Timespec.ModTime)
+	})
+}
+
+func TestLchtimes(t *testing.T) {
+	fileAndDirTest(t, func(name string) {
+		symname := filepath.Join(filepath.Dir(name), "sym-"+filepath.Base(name))
+		if err := os.Symlink(name, symname); err != nil {
+			t.Fatal(err.Error())
+		}
+		defer os.Remove(symname)
+
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		ts, err := Lchtimes(symname, Change{Atime: At(old), Mtime: At(old)})
+		if runtime.GOOS != "linux" {
+			if !errors.Is(err, ErrUnsupported) {
+				t.Errorf("got err %v, want %v", err, ErrUnsupported)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+		// the target is unchanged.
+		ts, err = Stat(symname)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.ModTime)
+	})
+
+	// Lchtimes of a regular file is Chtimes.
+	fileTest(t, func(f *os.File) {
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		ts, err := Lchtimes(f.Name(), Change{Mtime: At(old)})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.ModTime)
+	})
+}
+
+func TestChtimesErr(t *testing.T) {
+	if _, err := Chtimes("badfile?", Change{Mtime: Now}); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+	if _, err := Lchtimes("badfile?", Change{Mtime: Now}); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+
+	fileTest(t, func(f *os.File) {
+		f.Close()
+		if _, err := FileChtimes(f, Change{Mtime: Now}); err == nil {
+			t.Error("got nil err, but err was expected!")
+		}
+	})
+}
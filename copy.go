@@ -0,0 +1,174 @@
+package times
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrSameFile is returned by CopyFile when the source and the destination are the
+// same file.
+var ErrSameFile = errors.New("source and destination are the same file")
+
+// CopyOptions configures CopyTimes and CopyFile.
+type CopyOptions struct {
+	// NoFollow copies a symlink itself instead of its target:
+	// times are read with Lstat and written with Lchtimes.
+	NoFollow bool
+
+	// Fields are the times to copy, zero means AllFields.
+	// Only the access and modification times can be set, any change or birth
+	// time asked for is reported in CopyReport.Skipped.
+	Fields Field
+}
+
+func (o CopyOptions) wants() Field {
+	if o.Fields == 0 {
+		return AllFields
+	}
+	return o.Fields
+}
+
+// CopyReport describes what CopyTimes or CopyFile carried over.
+type CopyReport struct {
+	// Src holds the times of the source, read before anything was copied.
+	// It is the place to find the times which could not be set, e.g. Src.BirthTime().
+	Src Timespec
+
+	// Dst holds the times of the destination, read back after they were set.
+	Dst Timespec
+
+	// Copied are the times set on the destination.
+	Copied Field
+
+	// Skipped are the times which were asked for but could not be set,
+	// either because the source does not have them or because they can't be set.
+	Skipped Field
+}
+
+// CopyTimes sets the access and modification times of dst to those of src.
+func CopyTimes(src, dst string, opts CopyOptions) (CopyReport, error) {
+	stat, chtimes := Stat, Chtimes
+	if opts.NoFollow {
+		stat, chtimes = Lstat, Lchtimes
+	}
+
+	ts, err := stat(src)
+	if err != nil {
+		return CopyReport{}, err
+	}
+	return applyTimes(ts, opts, func(c Change) (Timespec, error) {
+		return chtimes(dst, c)
+	})
+}
+
+// CopyFile copies the contents and permissions of the file src to dst, creating or
+// truncating dst, and then copies the times as CopyTimes does. The times of src are
+// read before its contents, so reading it does not change the copied access time.
+// With opts.NoFollow a symlink src is copied as a symlink.
+// It fails with ErrSameFile if dst is src, or a link to it.
+func CopyFile(src, dst string, opts CopyOptions) (CopyReport, error) {
+	if opts.NoFollow {
+		fi, err := os.Lstat(src)
+		if err != nil {
+			return CopyReport{}, err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return copySymlink(src, dst, opts)
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return CopyReport{}, err
+	}
+	defer in.Close()
+
+	ts, err := StatFile(in)
+	if err != nil {
+		return CopyReport{}, err
+	}
+	fi, err := in.Stat()
+	if err != nil {
+		return CopyReport{}, err
+	}
+
+	// dst is only truncated once it is known not to be src, through a symlink or a
+	// hard link, which would lose the contents.
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE, fi.Mode().Perm())
+	if err != nil {
+		return CopyReport{}, err
+	}
+	outFi, err := out.Stat()
+	if err != nil {
+		out.Close()
+		return CopyReport{}, err
+	}
+	if os.SameFile(fi, outFi) {
+		out.Close()
+		return CopyReport{}, &os.LinkError{Op: "copy", Old: src, New: dst, Err: ErrSameFile}
+	}
+	if err := out.Truncate(0); err != nil {
+		out.Close()
+		return CopyReport{}, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return CopyReport{}, err
+	}
+	if err := out.Chmod(fi.Mode().Perm()); err != nil {
+		out.Close()
+		return CopyReport{}, err
+	}
+
+	// the times must be set after the last write.
+	r, err := applyTimes(ts, opts, func(c Change) (Timespec, error) {
+		return FileChtimes(out, c)
+	})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return r, err
+}
+
+func copySymlink(src, dst string, opts CopyOptions) (CopyReport, error) {
+	ts, err := Lstat(src)
+	if err != nil {
+		return CopyReport{}, err
+	}
+	target, err := os.Readlink(src)
+	if err != nil {
+		return CopyReport{}, err
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return CopyReport{}, err
+	}
+	return applyTimes(ts, opts, func(c Change) (Timespec, error) {
+		return Lchtimes(dst, c)
+	})
+}
+
+// applyTimes sets the times of ts wanted by opts with chtimes.
+func applyTimes(ts Timespec, opts CopyOptions, chtimes func(Change) (Timespec, error)) (CopyReport, error) {
+	wants := opts.wants()
+	r := CopyReport{Src: ts}
+
+	// a time src does not have is zero, which At omits.
+	var c Change
+	if wants&FieldAtime != 0 && !ts.AccessTime().IsZero() {
+		c.Atime = At(ts.AccessTime())
+		r.Copied |= FieldAtime
+	}
+	if wants&FieldMtime != 0 && !ts.ModTime().IsZero() {
+		c.Mtime = At(ts.ModTime())
+		r.Copied |= FieldMtime
+	}
+	r.Skipped = wants &^ r.Copied
+
+	dst, err := chtimes(c)
+	if err != nil {
+		return CopyReport{Src: ts, Skipped: wants}, err
+	}
+	r.Dst = dst
+	return r, nil
+}
@@ -0,0 +1,265 @@
+package times
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// creates a file with old times in a new dir and cleans it up after the test is run.
+func oldFileTest(t *testing.T, testFunc func(dir, name string, old time.Time)) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "src")
+	if err := ioutil.WriteFile(name, []byte("contents"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, old, old); err != nil {
+		t.Fatal(err)
+	}
+	testFunc(dir, name, old)
+}
+
+func TestCopyTimes(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		dst := filepath.Join(dir, "dst")
+		if err := ioutil.WriteFile(dst, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := CopyTimes(src, dst, CopyOptions{})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if r.Copied != FieldAtime|FieldMtime || r.Skipped != FieldCtime|FieldBtime {
+			t.Errorf("got copied %s and skipped %s", r.Copied, r.Skipped)
+		}
+		timespecTest(r.Src, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+		timespecTest(r.Dst, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+		ts, err := Stat(dst)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+	})
+}
+
+func TestCopyTimesFields(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		dst := filepath.Join(dir, "dst")
+		if err := ioutil.WriteFile(dst, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := CopyTimes(src, dst, CopyOptions{Fields: FieldMtime})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if r.Copied != FieldMtime || r.Skipped != 0 {
+			t.Errorf("got copied %s and skipped %s", r.Copied, r.Skipped)
+		}
+		timespecTest(r.Dst, newInterval(old, 0), t, Timespec.ModTime)
+		timespecTest(r.Dst, newInterval(time.Now(), time.Second), t, Timespec.AccessTime)
+	})
+}
+
+func TestCopyTimesMissing(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		dst := filepath.Join(dir, "dst")
+		if err := ioutil.WriteFile(dst, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		before, err := Stat(dst)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// times without an access time leave the one of dst alone.
+		ts := Times{Mtime: old, Source: SourceModTime}
+		r, err := applyTimes(ts, CopyOptions{}, func(c Change) (Timespec, error) {
+			return Chtimes(dst, c)
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if r.Copied != FieldMtime || r.Skipped != FieldAtime|FieldCtime|FieldBtime {
+			t.Errorf("got copied %s and skipped %s", r.Copied, r.Skipped)
+		}
+		timespecTest(r.Dst, newInterval(before.AccessTime(), 0), t, Timespec.AccessTime)
+		timespecTest(r.Dst, newInterval(old, 0), t, Timespec.ModTime)
+	})
+}
+
+func TestCopyTimesNoFollow(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only linux can set the times of a symlink")
+	}
+
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		srcLink := filepath.Join(dir, "src-link")
+		if err := os.Symlink(src, srcLink); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Lchtimes(srcLink, Change{Atime: At(old), Mtime: At(old)}); err != nil {
+			t.Fatal(err)
+		}
+
+		dst := filepath.Join(dir, "dst")
+		if err := ioutil.WriteFile(dst, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		dstLink := filepath.Join(dir, "dst-link")
+		if err := os.Symlink(dst, dstLink); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := CopyTimes(srcLink, dstLink, CopyOptions{NoFollow: true}); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		ts, err := Lstat(dstLink)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+		// the link target is untouched.
+		ts, err = Stat(dst)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.ModTime)
+	})
+}
+
+func TestCopyTimesErr(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		if _, err := CopyTimes(filepath.Join(dir, "missing"), src, CopyOptions{}); !os.IsNotExist(err) {
+			t.Errorf("got err %v, want not exist", err)
+		}
+
+		r, err := CopyTimes(src, filepath.Join(dir, "missing"), CopyOptions{})
+		if !os.IsNotExist(err) {
+			t.Errorf("got err %v, want not exist", err)
+		}
+		if r.Src == nil || r.Copied != 0 || r.Skipped != AllFields {
+			t.Errorf("unexpected report for a failed copy %+v", r)
+		}
+	})
+}
+
+func TestCopyFile(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		dst := filepath.Join(dir, "dst")
+		r, err := CopyFile(src, dst, CopyOptions{})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(r.Dst, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+		if r.Skipped&FieldAtime != 0 || r.Skipped&FieldMtime != 0 {
+			t.Errorf("got skipped %s", r.Skipped)
+		}
+
+		data, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !bytes.Equal(data, []byte("contents")) {
+			t.Errorf("got contents %q", data)
+		}
+
+		if runtime.GOOS != "windows" {
+			fi, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if fi.Mode().Perm() != 0640 {
+				t.Errorf("got mode %s, want %s", fi.Mode().Perm(), os.FileMode(0640))
+			}
+		}
+	})
+}
+
+func TestCopyFileSymlink(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		link := filepath.Join(dir, "link")
+		if err := os.Symlink(src, link); err != nil {
+			t.Fatal(err)
+		}
+
+		// following the link copies the contents.
+		dst := filepath.Join(dir, "dst")
+		r, err := CopyFile(link, dst, CopyOptions{})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(r.Dst, newInterval(old, 0), t, Timespec.ModTime)
+		if fi, err := os.Lstat(dst); err != nil || fi.Mode()&os.ModeSymlink != 0 {
+			t.Errorf("expected a regular file, got %v, %v", fi, err)
+		}
+
+		if runtime.GOOS != "linux" {
+			return
+		}
+
+		dstLink := filepath.Join(dir, "dst-link")
+		if _, err := CopyFile(link, dstLink, CopyOptions{NoFollow: true}); err != nil {
+			t.Fatal(err.Error())
+		}
+		target, err := os.Readlink(dstLink)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if target != src {
+			t.Errorf("got link target %q, want %q", target, src)
+		}
+	})
+}
+
+func TestCopyFileErr(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		if _, err := CopyFile(filepath.Join(dir, "missing"), filepath.Join(dir, "dst"), CopyOptions{}); !os.IsNotExist(err) {
+			t.Errorf("got err %v, want not exist", err)
+		}
+		if _, err := CopyFile(src, filepath.Join(dir, "missing", "dst"), CopyOptions{}); !os.IsNotExist(err) {
+			t.Errorf("got err %v, want not exist", err)
+		}
+	})
+}
+
+func TestCopyFileSame(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		link := filepath.Join(dir, "link")
+		if err := os.Symlink(src, link); err != nil {
+			t.Fatal(err)
+		}
+		hard := filepath.Join(dir, "hard")
+		if err := os.Link(src, hard); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, dst := range []string{src, link, hard} {
+			if _, err := CopyFile(src, dst, CopyOptions{}); !errors.Is(err, ErrSameFile) {
+				t.Errorf("%s: got err %v, want %v", dst, err, ErrSameFile)
+			}
+			data, err := ioutil.ReadFile(src)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if !bytes.Equal(data, []byte("contents")) {
+				t.Errorf("%s: got contents %q", dst, data)
+			}
+		}
+	})
+}
@@ -7,60 +7,75 @@ import (
 	"unsafe"
 )
 
-// Stat returns the Timespec for the given filename.
-func Stat(name string) (Timespec, error) {
-	ts, err := platformSpecficStat(name)
+// The ops of the *os.PathErrors returned by strict Statters.
+const (
+	opStat  = "stat"
+	opLstat = "lstat"
+	opFstat = "GetFileInformationByHandleEx"
+)
+
+func statxState() (statx, fallback bool) { return false, false }
+
+func (s *Statter) statInto(name string, dst *Times) error {
+	err := platformSpecficStat(name, dst)
 	if err == nil {
-		return ts, err
+		return nil
+	}
+	if s.strict {
+		return pathErr(opStat, name, err)
 	}
 
-	return stat(name, os.Stat)
+	return statInto(name, os.Stat, dst)
 }
 
-// Lstat returns the Timespec for the given filename, and does not follow Symlinks.
-func Lstat(name string) (Timespec, error) {
-	ts, err := platformSpecficLstat(name)
+func (s *Statter) lstatInto(name string, dst *Times) error {
+	err := platformSpecficLstat(name, dst)
 	if err == nil {
-		return ts, err
+		return nil
+	}
+	if s.strict {
+		return pathErr(opLstat, name, err)
 	}
 
-	return stat(name, os.Lstat)
-}
-
-type timespecEx struct {
-	atime
-	mtime
-	ctime
-	btime
+	return statInto(name, os.Lstat, dst)
 }
 
-// StatFile finds a Windows Timespec with ChangeTime.
-func StatFile(file *os.File) (Timespec, error) {
-	return statFile(syscall.Handle(file.Fd()))
+// statFileInto finds the Windows times with ChangeTime.
+func (s *Statter) statFileInto(file *os.File, dst *Times) error {
+	if err := statFile(syscall.Handle(file.Fd()), dst); err != nil {
+		return pathErr(opFstat, file.Name(), err)
+	}
+	return nil
 }
 
-func statFile(h syscall.Handle) (Timespec, error) {
+// statFile fills dst with the times of h, without a Timespec which would have
+// to be allocated.
+func statFile(h syscall.Handle, dst *Times) error {
 	var fileInfo fileBasicInfo
 	if err := getFileInformationByHandleEx(h, &fileInfo); err != nil {
-		return nil, err
+		return err
 	}
 
-	var t timespecEx
-	t.atime.v = time.Unix(0, fileInfo.LastAccessTime.Nanoseconds())
-	t.mtime.v = time.Unix(0, fileInfo.LastWriteTime.Nanoseconds())
-	t.ctime.v = time.Unix(0, fileInfo.ChangeTime.Nanoseconds())
-	t.btime.v = time.Unix(0, fileInfo.CreationTime.Nanoseconds())
-	return t, nil
+	*dst = Times{
+		Atime:    time.Unix(0, fileInfo.LastAccessTime.Nanoseconds()),
+		Mtime:    time.Unix(0, fileInfo.LastWriteTime.Nanoseconds()),
+		Ctime:    time.Unix(0, fileInfo.ChangeTime.Nanoseconds()),
+		Btime:    time.Unix(0, fileInfo.CreationTime.Nanoseconds()),
+		HasCtime: true,
+		HasBtime: true,
+		Source:   SourceFileHandle,
+	}
+	return nil
 }
 
-func platformSpecficLstat(name string) (Timespec, error) {
+func platformSpecficLstat(name string, dst *Times) error {
 	if findProcErr != nil {
-		return nil, findProcErr
+		return findProcErr
 	}
 
 	isSym, err := isSymlink(name)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	var attrs = uint32(syscall.FILE_FLAG_BACKUP_SEMANTICS)
@@ -68,7 +83,7 @@ func platformSpecficLstat(name string) (Timespec, error) {
 		attrs |= syscall.FILE_FLAG_OPEN_REPARSE_POINT
 	}
 
-	return openHandleAndStat(name, attrs)
+	return openHandleAndStat(name, attrs, dst)
 }
 
 func isSymlink(name string) (bool, error) {
@@ -79,28 +94,28 @@ func isSymlink(name string) (bool, error) {
 	return fi.Mode()&os.ModeSymlink != 0, nil
 }
 
-func platformSpecficStat(name string) (Timespec, error) {
+func platformSpecficStat(name string, dst *Times) error {
 	if findProcErr != nil {
-		return nil, findProcErr
+		return findProcErr
 	}
 
-	return openHandleAndStat(name, syscall.FILE_FLAG_BACKUP_SEMANTICS)
+	return openHandleAndStat(name, syscall.FILE_FLAG_BACKUP_SEMANTICS, dst)
 }
 
-func openHandleAndStat(name string, attrs uint32) (Timespec, error) {
+func openHandleAndStat(name string, attrs uint32, dst *Times) error {
 	pathp, e := syscall.UTF16PtrFromString(name)
 	if e != nil {
-		return nil, e
+		return e
 	}
 	h, e := syscall.CreateFile(pathp,
 		syscall.FILE_WRITE_ATTRIBUTES, syscall.FILE_SHARE_WRITE, nil,
 		syscall.OPEN_EXISTING, attrs, 0)
 	if e != nil {
-		return nil, e
+		return e
 	}
 	defer syscall.Close(h)
 
-	return statFile(h)
+	return statFile(h, dst)
 }
 
 var (
@@ -0,0 +1,202 @@
+package times
+
+import (
+	"container/list"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// DirCacheOptions configures a DirCache.
+type DirCacheOptions struct {
+	// Precision is the precision of the directory times on the filesystem,
+	// zero means it is guessed from the times: 2s if they are whole seconds
+	// (FAT has a 2s precision), 10ms otherwise (the tick of the clock most
+	// kernels use for file times, even when they report nanoseconds).
+	Precision time.Duration
+
+	// Size is the maximum number of cached listings, the least recently used
+	// one is evicted to make room. Zero means 256.
+	Size int
+
+	// Statter gets the times of the directories, nil means DefaultStatter.
+	// On NFS, a Statter with WithSync(ForceSync) bypasses the attribute cache.
+	Statter *Statter
+}
+
+// DirCacheStats are the counters of a DirCache.
+type DirCacheStats struct {
+	// Hits is the number of listings answered from the cache.
+	Hits uint64
+
+	// Misses is the number of listings which were read.
+	Misses uint64
+
+	// Untrusted is the number of listings which were read but not cached, since
+	// the directory changed in the last tick of its times, or while it was read.
+	Untrusted uint64
+}
+
+// DirCache caches directory listings, each one until the times of its directory
+// change: its modification and change times are updated whenever an entry is added,
+// removed or renamed. Checking the times is a single stat, which is cheaper than
+// reading a large directory, on NFS in particular.
+//
+// A change in the same tick of the filesystem clock as the last one does not change
+// the times, so a listing read right after a change is not cached, see
+// DirCacheOptions.Precision. Changes to the entries themselves (their size, their
+// times, ...) do not change the times of the directory, only the listing is cached.
+//
+// A DirCache is safe for concurrent use.
+type DirCache struct {
+	precision time.Duration
+	size      int
+	statter   *Statter
+
+	mu      sync.Mutex
+	lru     *list.List // of *dirCacheEntry, most recently used first
+	entries map[string]*list.Element
+	stats   DirCacheStats
+}
+
+type dirCacheEntry struct {
+	name    string
+	t       Times
+	entries []fs.DirEntry
+}
+
+// NewDirCache returns a DirCache configured by opts.
+func NewDirCache(opts DirCacheOptions) *DirCache {
+	c := &DirCache{
+		precision: opts.Precision,
+		size:      opts.Size,
+		statter:   opts.Statter,
+		lru:       list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+	if c.size <= 0 {
+		c.size = 256
+	}
+	if c.statter == nil {
+		c.statter = DefaultStatter
+	}
+	return c
+}
+
+// ReadDir returns the entries of the directory name sorted by filename, like
+// os.ReadDir, from the cache if the times of name did not change.
+func (c *DirCache) ReadDir(name string) ([]fs.DirEntry, error) {
+	var before Times
+	if err := c.statter.StatInto(name, &before); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[name]; ok {
+		if entry := e.Value.(*dirCacheEntry); sameDirTimes(entry.t, before) {
+			c.lru.MoveToFront(e)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return append([]fs.DirEntry(nil), entry.entries...), nil
+		}
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return entries, err
+	}
+	var after Times
+	if err := c.statter.StatInto(name, &after); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !sameDirTimes(before, after) || c.racy(after, time.Now()) {
+		c.stats.Untrusted++
+		if e, ok := c.entries[name]; ok {
+			c.remove(e)
+		}
+		return entries, nil
+	}
+	c.add(&dirCacheEntry{name: name, t: after, entries: append([]fs.DirEntry(nil), entries...)})
+	return entries, nil
+}
+
+// Invalidate forgets the listing of name.
+func (c *DirCache) Invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[name]; ok {
+		c.remove(e)
+	}
+}
+
+// Purge forgets every listing.
+func (c *DirCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// Len returns the number of cached listings, some may be out of date.
+func (c *DirCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Stats returns the counters of c.
+func (c *DirCache) Stats() DirCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *DirCache) add(entry *dirCacheEntry) {
+	if e, ok := c.entries[entry.name]; ok {
+		c.remove(e)
+	}
+	c.entries[entry.name] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *DirCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*dirCacheEntry).name)
+}
+
+// racy reports whether the last change of a directory with the times t may have been
+// in the current tick of its times, at now: another change in the same tick would not
+// change them.
+func (c *DirCache) racy(t Times, now time.Time) bool {
+	changed := t.Mtime
+	if t.HasCtime && t.Ctime.After(changed) {
+		changed = t.Ctime
+	}
+	precision := c.precision
+	if precision == 0 {
+		precision = guessPrecision(t)
+	}
+	return now.Sub(changed) < precision
+}
+
+// guessPrecision guesses the precision of the times t, see DirCacheOptions.Precision.
+func guessPrecision(t Times) time.Duration {
+	if t.Mtime.Nanosecond() == 0 && (!t.HasCtime || t.Ctime.Nanosecond() == 0) {
+		return 2 * time.Second
+	}
+	return 10 * time.Millisecond
+}
+
+// sameDirTimes reports whether a and b are the times of the same unchanged directory.
+func sameDirTimes(a, b Times) bool {
+	return a.id == b.id && a.Mtime.Equal(b.Mtime) &&
+		a.HasCtime == b.HasCtime && a.Ctime.Equal(b.Ctime)
+}
@@ -0,0 +1,165 @@
+package times
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func expectDirCacheStats(t *testing.T, c *DirCache, want DirCacheStats) {
+	t.Helper()
+	if got := c.Stats(); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+}
+
+func expectListing(t *testing.T, c *DirCache, dir string, want ...string) {
+	t.Helper()
+	entries, err := c.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got entries %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("got entries %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestDirCache(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "b"), nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	c := NewDirCache(DirCacheOptions{})
+
+	// let the tick of the last change pass, so the listings are cached.
+	tick := func() { time.Sleep(20 * time.Millisecond) }
+	tick()
+	expectListing(t, c, dir, "b")
+	entries, err := c.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	entries[0] = nil // not the cached slice.
+	expectListing(t, c, dir, "b")
+	expectDirCacheStats(t, c, DirCacheStats{Hits: 2, Misses: 1})
+
+	// a new entry changes the times of dir.
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	tick()
+	expectListing(t, c, dir, "a", "b")
+	expectDirCacheStats(t, c, DirCacheStats{Hits: 2, Misses: 2})
+
+	// so does a rename.
+	if err := os.Rename(filepath.Join(dir, "a"), filepath.Join(dir, "c")); err != nil {
+		t.Fatal(err.Error())
+	}
+	tick()
+	expectListing(t, c, dir, "b", "c")
+	expectListing(t, c, dir, "b", "c")
+	expectDirCacheStats(t, c, DirCacheStats{Hits: 3, Misses: 3})
+
+	c.Invalidate(dir)
+	expectListing(t, c, dir, "b", "c")
+	c.Purge()
+	expectListing(t, c, dir, "b", "c")
+	expectDirCacheStats(t, c, DirCacheStats{Hits: 3, Misses: 5})
+
+	if _, err := c.ReadDir(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+}
+
+func TestDirCacheRacy(t *testing.T) {
+	dir := t.TempDir()
+	c := NewDirCache(DirCacheOptions{Precision: time.Hour})
+
+	// dir changed in the last hour, which is a tick.
+	expectListing(t, c, dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectListing(t, c, dir, "a")
+	expectDirCacheStats(t, c, DirCacheStats{Misses: 2, Untrusted: 2})
+}
+
+func TestDirCacheRacyTimes(t *testing.T) {
+	now := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	fine := Times{Mtime: now.Add(-5 * time.Millisecond), Ctime: now.Add(-20 * time.Millisecond), HasCtime: true}
+	coarse := Times{Mtime: now.Truncate(time.Second).Add(-time.Second)}
+	tests := []struct {
+		name      string
+		precision time.Duration
+		t         Times
+		want      bool
+	}{
+		{"fine", 0, fine, true},
+		{"fine ctime", 0, Times{Mtime: fine.Ctime, Ctime: fine.Mtime, HasCtime: true}, true},
+		{"fine old", 0, Times{Mtime: fine.Ctime}, false},
+		{"coarse", 0, coarse, true},
+		{"coarse old", 0, Times{Mtime: coarse.Mtime.Add(-2 * time.Second)}, false},
+		{"precision", time.Millisecond, fine, false},
+	}
+	for _, test := range tests {
+		c := NewDirCache(DirCacheOptions{Precision: test.precision})
+		if got := c.racy(test.t, now); got != test.want {
+			t.Errorf("%s: got racy %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGuessPrecision(t *testing.T) {
+	sec := time.Unix(1000, 0)
+	tests := []struct {
+		t    Times
+		want time.Duration
+	}{
+		{Times{Mtime: sec}, 2 * time.Second},
+		{Times{Mtime: sec, Ctime: sec, HasCtime: true}, 2 * time.Second},
+		{Times{Mtime: sec, Ctime: sec.Add(1), HasCtime: true}, 10 * time.Millisecond},
+		{Times{Mtime: sec.Add(time.Millisecond)}, 10 * time.Millisecond},
+	}
+	for _, test := range tests {
+		if got := guessPrecision(test.t); got != test.want {
+			t.Errorf("got precision %s for %+v, want %s", got, test.t, test.want)
+		}
+	}
+}
+
+func TestDirCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	c := NewDirCache(DirCacheOptions{Precision: time.Nanosecond, Size: 2})
+	list := func(name string) { expectListing(t, c, filepath.Join(dir, name)) }
+
+	list("a")
+	list("b")
+	list("a") // b is the least recently used now.
+	list("c")
+	if c.Len() != 2 {
+		t.Errorf("got %d cached listings, want 2", c.Len())
+	}
+	expectDirCacheStats(t, c, DirCacheStats{Hits: 1, Misses: 3})
+
+	list("a")
+	expectDirCacheStats(t, c, DirCacheStats{Hits: 2, Misses: 3})
+	list("b")
+	expectDirCacheStats(t, c, DirCacheStats{Hits: 2, Misses: 4})
+}
@@ -0,0 +1,60 @@
+package times
+
+import (
+	"errors"
+	"strconv"
+	"syscall"
+)
+
+// ErrUnsupported is returned for operations which are not supported on the running platform.
+var ErrUnsupported = errors.New("not supported on this platform")
+
+// StatFd returns the Timespec for the open file descriptor fd (a HANDLE on windows).
+// Unlike StatFile it works for any descriptor: pipes, sockets, memfds and O_PATH descriptors.
+// fd is not closed and must stay open for the duration of the call.
+func StatFd(fd uintptr) (Timespec, error) {
+	return DefaultStatter.StatFd(fd)
+}
+
+// StatRawConn returns the Timespec for the descriptor behind rc,
+// e.g. from (*net.TCPConn).SyscallConn or (*os.File).SyscallConn.
+func StatRawConn(rc syscall.RawConn) (Timespec, error) {
+	return DefaultStatter.StatRawConn(rc)
+}
+
+// StatFd returns the Timespec for the open file descriptor fd, see StatFd.
+func (s *Statter) StatFd(fd uintptr) (Timespec, error) {
+	var t Times
+	if err := s.StatFdInto(fd, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// StatFdInto fills dst with the times for the open file descriptor fd, see StatFd.
+func (s *Statter) StatFdInto(fd uintptr, dst *Times) error {
+	if err := s.statFdInto(fd, dst); err != nil {
+		return err
+	}
+	return s.check(opFstat, fdName(fd), dst)
+}
+
+// StatRawConn returns the Timespec for the descriptor behind rc, see StatRawConn.
+func (s *Statter) StatRawConn(rc syscall.RawConn) (Timespec, error) {
+	var t Times
+	var statErr error
+	if err := rc.Control(func(fd uintptr) {
+		statErr = s.StatFdInto(fd, &t)
+	}); err != nil {
+		return nil, err
+	}
+	if statErr != nil {
+		return nil, statErr
+	}
+	return t, nil
+}
+
+// fdName is the path used in the *os.PathErrors of StatFd.
+func fdName(fd uintptr) string {
+	return "fd " + strconv.FormatUint(uint64(fd), 10)
+}
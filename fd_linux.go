@@ -0,0 +1,17 @@
+package times
+
+import "golang.org/x/sys/unix"
+
+func (s *Statter) statFdInto(fd uintptr, dst *Times) error {
+	if isStatXSupported() {
+		err := s.statXInto(int(fd), "", unix.AT_EMPTY_PATH, dst)
+		if err == nil || !isStatXUnsupported(err) {
+			return pathErr(opFstat, fdName(fd), err)
+		}
+		// Fallback.
+	}
+	if s.strict {
+		return pathErr(opFstat, fdName(fd), ErrStatxUnavailable)
+	}
+	return pathErr("fstat", fdName(fd), fstatatInto(int(fd), "", unix.AT_EMPTY_PATH, dst))
+}
@@ -0,0 +1,165 @@
+package times
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func testStatFd(t *testing.T, fd uintptr) {
+	t.Helper()
+
+	ts, err := StatFd(fd)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	timespecTest(ts, newInterval(time.Now(), time.Second), t)
+}
+
+func testStatRawConn(t *testing.T, sc syscall.Conn) {
+	t.Helper()
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ts, err := StatRawConn(rc)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	timespecTest(ts, newInterval(time.Now(), time.Second), t)
+}
+
+// sockfs does not keep times on every kernel, so only check that sockets can be stat'd.
+func testStatSocket(t *testing.T, ts Timespec, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if src := SourceOf(ts); src != SourceStatx && src != SourceStat {
+		t.Errorf("unexpected source %s", src)
+	}
+}
+
+func TestStatFdPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer r.Close()
+	defer w.Close()
+
+	testStatRawConn(t, r)
+	testStatRawConn(t, w)
+}
+
+func TestStatFdSocket(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer l.Close()
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer c.Close()
+
+	rc, err := l.(*net.TCPListener).SyscallConn()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ts, err := StatRawConn(rc)
+	testStatSocket(t, ts, err)
+
+	rc, err = c.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ts, err = StatRawConn(rc)
+	testStatSocket(t, ts, err)
+}
+
+func TestStatFdSocketpair(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer unix.Close(fds[0])
+	defer unix.Close(fds[1])
+
+	ts, err := StatFd(uintptr(fds[0]))
+	testStatSocket(t, ts, err)
+}
+
+func TestStatFdMemfd(t *testing.T) {
+	fd, err := unix.MemfdCreate("times", unix.MFD_CLOEXEC)
+	if err != nil {
+		t.Skip("memfd_create is not supported:", err)
+	}
+	defer unix.Close(fd)
+
+	testStatFd(t, uintptr(fd))
+}
+
+func TestStatFdOPath(t *testing.T) {
+	fileAndDirTest(t, func(name string) {
+		symname := filepath.Join(filepath.Dir(name), "sym-"+filepath.Base(name))
+		if err := os.Symlink(name, symname); err != nil {
+			t.Fatal(err.Error())
+		}
+		defer os.Remove(symname)
+
+		// an O_PATH|O_NOFOLLOW descriptor refers to the symlink itself.
+		fd, err := unix.Open(symname, unix.O_PATH|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		defer unix.Close(fd)
+
+		testStatFd(t, uintptr(fd))
+	})
+}
+
+func TestStatFdFallback(t *testing.T) {
+	restore := setStatx(unsupportedStatx)
+	defer restore()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer r.Close()
+	defer w.Close()
+
+	rc, err := r.SyscallConn()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ts, err := StatRawConn(rc)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	timespecTest(ts, newInterval(time.Now(), time.Second), t)
+	if src := SourceOf(ts); src != SourceStat {
+		t.Errorf("SourceOf() = %s, want %s", src, SourceStat)
+	}
+}
+
+func TestStatFdErr(t *testing.T) {
+	fd, err := unix.Open(os.DevNull, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	unix.Close(fd)
+
+	_, err = StatFd(uintptr(fd))
+	checkPathError(t, err, "fstatx", fdName(uintptr(fd)), unix.EBADF)
+}
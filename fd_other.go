@@ -0,0 +1,32 @@
+//go:build !linux && !windows && !plan9
+// +build !linux,!windows,!plan9
+
+package times
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func (s *Statter) statFdInto(fd uintptr, dst *Times) error {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(fd), &st); err != nil {
+		return pathErr(opFstat, fdName(fd), err)
+	}
+	dst.setFileInfo(statFileInfo{&st}, SourceStat)
+	return nil
+}
+
+// statFileInfo lets setSys and fileIDOf read a *syscall.Stat_t
+// which did not come from the os package.
+type statFileInfo struct {
+	st *syscall.Stat_t
+}
+
+func (fi statFileInfo) Name() string       { return "" }
+func (fi statFileInfo) Size() int64        { return int64(fi.st.Size) }
+func (fi statFileInfo) Mode() os.FileMode  { return 0 }
+func (fi statFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi statFileInfo) IsDir() bool        { return false }
+func (fi statFileInfo) Sys() interface{}   { return fi.st }
@@ -0,0 +1,5 @@
+package times
+
+func (s *Statter) statFdInto(fd uintptr, dst *Times) error {
+	return pathErr(opFstat, fdName(fd), ErrUnsupported)
+}
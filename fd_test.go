@@ -0,0 +1,45 @@
+package times
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStatFd(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		ts, err := StatFd(f.Fd())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+	})
+}
+
+func TestStatRawConn(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		rc, err := f.SyscallConn()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		ts, err := StatRawConn(rc)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+	})
+}
+
+func TestStatRawConnErr(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		rc, err := f.SyscallConn()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		f.Close()
+
+		if _, err := StatRawConn(rc); err == nil {
+			t.Error("got nil err, but err was expected!")
+		}
+	})
+}
@@ -0,0 +1,10 @@
+package times
+
+import "syscall"
+
+func (s *Statter) statFdInto(fd uintptr, dst *Times) error {
+	if err := statFile(syscall.Handle(fd), dst); err != nil {
+		return pathErr(opFstat, fdName(fd), err)
+	}
+	return nil
+}
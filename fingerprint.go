@@ -0,0 +1,170 @@
+package times
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+	"time"
+)
+
+// Fingerprint identifies a version of a file by what stat(2) says about it, it is
+// much harder to fool than the modification time alone, which tools like touch -r
+// or tar -x set back: the change time can't be set, so an edit which resets the
+// modification time still moves it.
+type Fingerprint struct {
+	// Dev and Ino are the device and inode numbers, zero if not known.
+	Dev, Ino uint64
+
+	Size  int64
+	Mtime time.Time
+
+	// Ctime and Btime are only compared if HasCtime and HasBtime are set.
+	Ctime, Btime       time.Time
+	HasCtime, HasBtime bool
+
+	// Racy is set if the file changed (Mtime or Ctime) right before the Fingerprint
+	// was taken, it may change again without its times changing, in the same tick of
+	// the filesystem clock.
+	Racy bool
+
+	// Hash is the SHA-256 of the contents, it is only set if HasHash is.
+	Hash    [sha256.Size]byte
+	HasHash bool
+}
+
+// FingerprintOptions configures NewFingerprint and CheckFingerprint.
+type FingerprintOptions struct {
+	// RacyWindow is how recent a change must be for a Fingerprint to be racy,
+	// zero means 1s.
+	RacyWindow time.Duration
+
+	// Hash hashes the contents of the racy files, so Compare can tell if they changed.
+	Hash bool
+}
+
+func (o FingerprintOptions) racyWindow() time.Duration {
+	if o.RacyWindow == 0 {
+		return time.Second
+	}
+	return o.RacyWindow
+}
+
+// NewFingerprint returns the Fingerprint of the file name (following symlinks).
+func NewFingerprint(name string, opts FingerprintOptions) (Fingerprint, error) {
+	var t Times
+	if err := stableSample(name, false, &t); err != nil {
+		return Fingerprint{}, err
+	}
+	fp := Fingerprint{
+		Dev:      t.id.dev,
+		Ino:      t.id.ino,
+		Size:     t.size,
+		Mtime:    t.Mtime,
+		Ctime:    t.Ctime,
+		Btime:    t.Btime,
+		HasCtime: t.HasCtime,
+		HasBtime: t.HasBtime,
+	}
+
+	changed := t.Mtime
+	if t.HasCtime && t.Ctime.After(changed) {
+		changed = t.Ctime
+	}
+	fp.Racy = time.Since(changed) < opts.racyWindow()
+	if fp.Racy && opts.Hash {
+		if err := fp.hash(name); err != nil {
+			return fp, err
+		}
+	}
+	return fp, nil
+}
+
+func (fp *Fingerprint) hash(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	h.Sum(fp.Hash[:0])
+	fp.HasHash = true
+	return nil
+}
+
+// Verdict is the result of Compare.
+type Verdict int
+
+const (
+	// Same means the file did not change.
+	Same Verdict = iota
+
+	// Suspicious means the file may have changed: only its change time moved, which
+	// a chmod does as well as an edit whose modification time was set back, or the
+	// old Fingerprint was racy. Only hashing the contents can tell.
+	Suspicious
+
+	// Modified means the file changed, or was replaced.
+	Modified
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Same:
+		return "same"
+	case Suspicious:
+		return "suspicious"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Compare compares the Fingerprints of a file taken at two times. If both of them
+// have a Hash, it settles what the times can't: a Suspicious change is Same or
+// Modified depending on the hashes.
+func Compare(old, new Fingerprint) Verdict {
+	if old.Dev != new.Dev || old.Ino != new.Ino || old.Size != new.Size ||
+		!old.Mtime.Equal(new.Mtime) ||
+		old.HasBtime && new.HasBtime && !old.Btime.Equal(new.Btime) {
+		return Modified
+	}
+	v := Same
+	if old.Racy || old.HasCtime && new.HasCtime && !old.Ctime.Equal(new.Ctime) {
+		v = Suspicious
+	}
+	if v == Suspicious && old.HasHash && new.HasHash {
+		if old.Hash != new.Hash {
+			return Modified
+		}
+		return Same
+	}
+	return v
+}
+
+// Changed reports whether the file may have changed between the Fingerprints old
+// and new, a Suspicious change counts as a change.
+func Changed(old, new Fingerprint) bool {
+	return Compare(old, new) != Same
+}
+
+// CheckFingerprint takes a new Fingerprint of the file name and reports whether the
+// file changed since old. If the times alone can't tell and old has a Hash, the
+// contents are hashed to decide, if opts.Hash is set.
+func CheckFingerprint(name string, old Fingerprint, opts FingerprintOptions) (Fingerprint, bool, error) {
+	fp, err := NewFingerprint(name, opts)
+	if err != nil {
+		return fp, false, err
+	}
+	v := Compare(old, fp)
+	if v == Suspicious && opts.Hash && old.HasHash && !fp.HasHash {
+		if err := fp.hash(name); err != nil {
+			return fp, false, err
+		}
+		v = Compare(old, fp)
+	}
+	return fp, v != Same, nil
+}
@@ -0,0 +1,182 @@
+package times
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		// the change time of the file is recent, it was just set up.
+		opts := FingerprintOptions{Hash: true, RacyWindow: time.Nanosecond}
+		fp, err := NewFingerprint(name, opts)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if fp.Racy || fp.HasHash || !fp.Mtime.Equal(old) {
+			t.Errorf("unexpected fingerprint %+v of an old file", fp)
+		}
+
+		same, changed, err := CheckFingerprint(name, fp, opts)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if changed || Compare(fp, same) != Same {
+			t.Errorf("got changed %v, want the same file", changed)
+		}
+
+		// an edit of the same size, with the modification time set back.
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		for i := range data {
+			data[i] = 'x'
+		}
+		if err := ioutil.WriteFile(name, data, 0640); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Chtimes(name, old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+		edited, err := NewFingerprint(name, opts)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		want := Suspicious
+		if !fp.HasCtime {
+			// nothing tells the edit apart.
+			want = Same
+		}
+		if got := Compare(fp, edited); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+
+		// a bigger file.
+		if err := ioutil.WriteFile(name, append(data, 'x'), 0640); err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, changed, err := CheckFingerprint(name, fp, opts); err != nil || !changed {
+			t.Errorf("got changed %v, %v, want true", changed, err)
+		}
+	})
+}
+
+func TestFingerprintReplaced(t *testing.T) {
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		fp, err := NewFingerprint(name, FingerprintOptions{})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if fp.Ino == 0 {
+			t.Skip("inode numbers not available")
+		}
+
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		tmp := filepath.Join(dir, "tmp")
+		if err := ioutil.WriteFile(tmp, data, 0640); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Chtimes(tmp, old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Rename(tmp, name); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		replaced, err := NewFingerprint(name, FingerprintOptions{})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if got := Compare(fp, replaced); got != Modified {
+			t.Errorf("got %s, want %s", got, Modified)
+		}
+	})
+}
+
+func TestFingerprintRacy(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "racy")
+	if err := ioutil.WriteFile(name, []byte("racy"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	opts := FingerprintOptions{Hash: true, RacyWindow: time.Hour}
+	fp, err := NewFingerprint(name, opts)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !fp.Racy || !fp.HasHash {
+		t.Fatalf("got %+v, want a racy fingerprint with a hash", fp)
+	}
+
+	// without hashes a racy fingerprint can't be trusted.
+	if _, changed, err := CheckFingerprint(name, fp, FingerprintOptions{RacyWindow: time.Hour}); err != nil || !changed {
+		t.Errorf("got changed %v, %v, want true", changed, err)
+	}
+	if _, changed, err := CheckFingerprint(name, fp, opts); err != nil || changed {
+		t.Errorf("got changed %v, %v, want false", changed, err)
+	}
+
+	// changed in the same tick.
+	st, err := Stat(name)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := ioutil.WriteFile(name, []byte("RACY"), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.Chtimes(name, st.AccessTime(), st.ModTime()); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, changed, err := CheckFingerprint(name, fp, opts); err != nil || !changed {
+		t.Errorf("got changed %v, %v, want true", changed, err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	base := Fingerprint{
+		Dev: 1, Ino: 2, Size: 3,
+		Mtime: now, Ctime: now, Btime: now, HasCtime: true, HasBtime: true,
+	}
+	hashed := func(fp Fingerprint, b byte) Fingerprint {
+		fp.Hash[0], fp.HasHash = b, true
+		return fp
+	}
+	tests := []struct {
+		name string
+		old  Fingerprint
+		new  func(fp Fingerprint) Fingerprint
+		want Verdict
+	}{
+		{"same", base, func(fp Fingerprint) Fingerprint { return fp }, Same},
+		{"dev", base, func(fp Fingerprint) Fingerprint { fp.Dev++; return fp }, Modified},
+		{"ino", base, func(fp Fingerprint) Fingerprint { fp.Ino++; return fp }, Modified},
+		{"size", base, func(fp Fingerprint) Fingerprint { fp.Size++; return fp }, Modified},
+		{"mtime", base, func(fp Fingerprint) Fingerprint { fp.Mtime = now.Add(1); return fp }, Modified},
+		{"btime", base, func(fp Fingerprint) Fingerprint { fp.Btime = now.Add(1); return fp }, Modified},
+		{"no btime", base, func(fp Fingerprint) Fingerprint { fp.HasBtime = false; return fp }, Same},
+		{"ctime", base, func(fp Fingerprint) Fingerprint { fp.Ctime = now.Add(1); return fp }, Suspicious},
+		{"no ctime", base, func(fp Fingerprint) Fingerprint { fp.HasCtime, fp.Ctime = false, time.Time{}; return fp }, Same},
+		{"racy", Fingerprint{Racy: true}, func(fp Fingerprint) Fingerprint { fp.Racy = false; return fp }, Suspicious},
+		{"same hash", hashed(base, 1), func(fp Fingerprint) Fingerprint { fp.Ctime = now.Add(1); return fp }, Same},
+		{"other hash", hashed(base, 1), func(fp Fingerprint) Fingerprint { fp.Ctime = now.Add(1); return hashed(fp, 2) }, Modified},
+		{"hash of modified", hashed(base, 1), func(fp Fingerprint) Fingerprint { fp.Size++; return fp }, Modified},
+	}
+	for _, test := range tests {
+		if got := Compare(test.old, test.new(test.old)); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+		if got := Changed(test.old, test.new(test.old)); got != (test.want != Same) {
+			t.Errorf("%s: got changed %v", test.name, got)
+		}
+	}
+	if s := Verdict(-1).String(); s != "unknown" {
+		t.Errorf("got %q, want unknown", s)
+	}
+}
@@ -0,0 +1,47 @@
+package times
+
+import (
+	"io/fs"
+	"os"
+)
+
+// TimespecProvider can be implemented by an os.FileInfo, or the value returned by
+// its Sys() method, to supply times to Get and StatFS for files which are not
+// backed by the operating system (archives, embedded or virtual filesystems).
+type TimespecProvider interface {
+	Timespec() Timespec
+}
+
+// TimesFS is an fs.FS which can supply the times of its files directly,
+// StatFS uses it in preference to fs.Stat.
+type TimesFS interface {
+	fs.FS
+
+	// StatTimes returns the Timespec for the named file.
+	StatTimes(name string) (Timespec, error)
+}
+
+// StatFS returns the Timespec for the named file in fsys.
+// If fsys is a TimesFS its StatTimes method is used, otherwise the result of
+// fs.Stat is passed to Get.
+func StatFS(fsys fs.FS, name string) (Timespec, error) {
+	if tfs, ok := fsys.(TimesFS); ok {
+		return tfs.StatTimes(name)
+	}
+
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return Get(fi), nil
+}
+
+func providedTimespec(fi os.FileInfo) (Timespec, bool) {
+	if p, ok := fi.(TimespecProvider); ok {
+		return p.Timespec(), true
+	}
+	if p, ok := fi.Sys().(TimespecProvider); ok {
+		return p.Timespec(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,116 @@
+package times
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type providerSys struct {
+	ts Timespec
+}
+
+func (p providerSys) Timespec() Timespec { return p.ts }
+
+type timesFS struct {
+	fstest.MapFS
+	ts Timespec
+}
+
+func (fsys timesFS) StatTimes(name string) (Timespec, error) {
+	if _, err := fs.Stat(fsys.MapFS, name); err != nil {
+		return nil, err
+	}
+	return fsys.ts, nil
+}
+
+func fullTimes(t time.Time) Times {
+	return Times{Atime: t, Mtime: t, Ctime: t, Btime: t, HasCtime: true, HasBtime: true}
+}
+
+func TestGetModTimeOnly(t *testing.T) {
+	mtime := time.Now().Add(-time.Hour)
+	fsys := fstest.MapFS{"file": &fstest.MapFile{Data: []byte("data"), ModTime: mtime}}
+
+	fi, err := fs.Stat(fsys, "file")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ts := Get(fi)
+	if !ts.ModTime().Equal(mtime) {
+		t.Errorf("ModTime() = %v, want %v", ts.ModTime(), mtime)
+	}
+	if !ts.AccessTime().IsZero() {
+		t.Errorf("AccessTime() = %v, want zero time", ts.AccessTime())
+	}
+	if ts.HasChangeTime() || ts.HasBirthTime() {
+		t.Error("expected no change or birth time")
+	}
+	if src := SourceOf(ts); src != SourceModTime {
+		t.Errorf("SourceOf() = %s, want %s", src, SourceModTime)
+	}
+}
+
+func TestGetProvider(t *testing.T) {
+	want := fullTimes(time.Now().Add(-time.Hour))
+	fsys := fstest.MapFS{"file": &fstest.MapFile{Sys: providerSys{want}}}
+
+	ts, err := StatFS(fsys, "file")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if got, ok := ts.(Times); !ok || !got.Equal(want) {
+		t.Errorf("StatFS() = %v, want %v", ts, want)
+	}
+}
+
+func TestStatFSTimesFS(t *testing.T) {
+	want := fullTimes(time.Now().Add(-time.Hour))
+	fsys := timesFS{
+		MapFS: fstest.MapFS{"dir/file": &fstest.MapFile{}},
+		ts:    want,
+	}
+
+	ts, err := StatFS(fsys, "dir/file")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if got, ok := ts.(Times); !ok || !got.Equal(want) {
+		t.Errorf("StatFS() = %v, want %v", ts, want)
+	}
+
+	if _, err := StatFS(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got err %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestStatFSDirFS(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		ts, err := StatFS(os.DirFS(filepath.Dir(f.Name())), filepath.Base(f.Name()))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+		if src := SourceOf(ts); src != SourceFileInfo {
+			t.Errorf("SourceOf() = %s, want %s", src, SourceFileInfo)
+		}
+	})
+}
+
+func TestStatFSErr(t *testing.T) {
+	if _, err := StatFS(fstest.MapFS{}, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got err %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestModTimeOnlyFields(t *testing.T) {
+	ts := Times{Mtime: time.Now(), Source: SourceModTime}
+	if f := ts.fields(); f != FieldMtime {
+		t.Errorf("fields() = %s, want %s", f, FieldMtime)
+	}
+}
@@ -0,0 +1,352 @@
+package times
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// IndexVersion is the version of the format written by Index.Save.
+const IndexVersion = 1
+
+var (
+	// ErrIndexCorrupt is returned by LoadIndex for a file which is not an index,
+	// or which was damaged.
+	ErrIndexCorrupt = errors.New("index corrupt")
+
+	// ErrIndexVersion is returned by LoadIndex for an index written in a newer format.
+	ErrIndexVersion = errors.New("unsupported index version")
+)
+
+var indexMagic = [4]byte{'T', 'I', 'D', 'X'}
+
+const (
+	indexHeaderSize  = 12 // magic, version, count
+	indexEntrySize   = 2 + 3*8 + 4*12 + 1 + 1 + 4
+	indexTrailerSize = 4 // crc32
+)
+
+const (
+	indexHasCtime = 1 << iota
+	indexHasBtime
+	indexRacy
+)
+
+// IndexEntry is what an Index knows about a path.
+type IndexEntry struct {
+	Path string
+
+	// Dev and Ino are the device and inode numbers, zero if not known.
+	Dev, Ino uint64
+
+	Size  int64
+	Times Times
+
+	// Racy is set for an entry whose modification time is not older than the Index
+	// it was loaded from or saved to: it may have been changed again in the same tick
+	// of the filesystem clock, right after it was stat'd, without its times changing.
+	// Verify never reports a racy entry as clean, its contents must be checked.
+	Racy bool
+}
+
+// Index is a persistent cache of the times, inodes and sizes of paths, in the
+// style of the git index: it is saved to a file so a later process can tell which
+// paths changed with a stat of each of them, without reading their contents.
+//
+// An Index is not safe for concurrent use.
+type Index struct {
+	entries map[string]IndexEntry
+
+	// written is the modification time of the index file, the entries not older
+	// than it are racy.
+	written time.Time
+}
+
+// NewIndex returns an empty Index.
+func NewIndex() *Index {
+	return &Index{entries: make(map[string]IndexEntry)}
+}
+
+// Len returns the number of entries in ix.
+func (ix *Index) Len() int {
+	return len(ix.entries)
+}
+
+// Paths returns the paths of the entries of ix, sorted.
+func (ix *Index) Paths() []string {
+	paths := make([]string, 0, len(ix.entries))
+	for path := range ix.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Get returns the entry for path, if there is one.
+func (ix *Index) Get(path string) (IndexEntry, bool) {
+	e, ok := ix.entries[path]
+	return e, ok
+}
+
+// Put adds or replaces the entry for e.Path, for example with an entry returned
+// by Verify once its contents were checked.
+func (ix *Index) Put(e IndexEntry) {
+	ix.entries[e.Path] = e
+}
+
+// Remove removes the entry for path.
+func (ix *Index) Remove(path string) {
+	delete(ix.entries, path)
+}
+
+// Update stats path, without following symlinks, and stores the result.
+func (ix *Index) Update(path string) (IndexEntry, error) {
+	e, err := statIndexEntry(path)
+	if err != nil {
+		return e, err
+	}
+	ix.entries[path] = e
+	return e, nil
+}
+
+// Verify stats path, without following symlinks, and reports whether its entry
+// is clean: it is not racy, and the device and inode numbers, the size, the
+// modification time and the change time (where available) are unchanged.
+// The fresh entry is returned, but not stored, see Put and Update.
+func (ix *Index) Verify(path string) (IndexEntry, bool, error) {
+	e, err := statIndexEntry(path)
+	if err != nil {
+		return e, false, err
+	}
+	old, ok := ix.entries[path]
+	if !ok || old.Racy {
+		return e, false, nil
+	}
+	clean := old.Dev == e.Dev && old.Ino == e.Ino && old.Size == e.Size &&
+		old.Times.Mtime.Equal(e.Times.Mtime) &&
+		old.Times.HasCtime == e.Times.HasCtime && old.Times.Ctime.Equal(e.Times.Ctime)
+	return e, clean, nil
+}
+
+func statIndexEntry(path string) (IndexEntry, error) {
+	e := IndexEntry{Path: path}
+	if err := stableSample(path, true, &e.Times); err != nil {
+		return e, err
+	}
+	e.Dev, e.Ino, e.Size = e.Times.id.dev, e.Times.id.ino, e.Times.size
+	return e, nil
+}
+
+// markRacy sets Racy on the entries not older than written.
+// It returns whether any entry was not racy before.
+func (ix *Index) markRacy(written time.Time) bool {
+	changed := false
+	for path, e := range ix.entries {
+		if !e.Racy && !e.Times.Mtime.Before(written) {
+			e.Racy = true
+			ix.entries[path] = e
+			changed = true
+		}
+	}
+	return changed
+}
+
+// LoadIndex reads the Index saved to name. The entries whose modification time
+// is not older than the file are marked racy, as are the racy entries when it
+// was saved.
+func LoadIndex(name string) (*Index, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ts, err := StatFile(f)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := decodeIndex(data)
+	if err != nil {
+		return nil, &os.PathError{Op: "load index", Path: name, Err: err}
+	}
+	ix.written = ts.ModTime()
+	ix.markRacy(ix.written)
+	return ix, nil
+}
+
+// Save writes ix to name. The file is replaced atomically, so a crash leaves
+// either the old or the new index behind, see SyncError.
+func (ix *Index) Save(name string) error {
+	data, err := ix.encode()
+	if err != nil {
+		return err
+	}
+	return replaceFile(name, data, 0644, func(f *os.File) error {
+		// the entries not older than the file are racy for whoever loads it, and
+		// must stay racy if it is loaded and saved again, so they are flagged.
+		ts, err := StatFile(f)
+		if err != nil {
+			return err
+		}
+		ix.written = ts.ModTime()
+		if !ix.markRacy(ix.written) {
+			return nil
+		}
+		if data, err = ix.encode(); err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(data, 0); err != nil {
+			return err
+		}
+		return f.Sync()
+	})
+}
+
+// encode returns ix in the IndexVersion format, the entries sorted by path:
+//
+//	"TIDX" version:uint32 count:uint32
+//	count * (len:uint16 path dev:uint64 ino:uint64 size:int64
+//	         4 * (sec:int64 nsec:uint32) source:uint8 flags:uint8 statxMask:uint32)
+//	crc32:uint32
+//
+// All the integers are big endian, the times are atime, mtime, ctime and btime.
+func (ix *Index) encode() ([]byte, error) {
+	paths := ix.Paths()
+	size := indexHeaderSize + indexTrailerSize + len(paths)*indexEntrySize
+	for _, path := range paths {
+		if len(path) > math.MaxUint16 {
+			return nil, &os.PathError{Op: "save index", Path: path, Err: errors.New("path too long")}
+		}
+		size += len(path)
+	}
+
+	b := make([]byte, 0, size)
+	b = append(b, indexMagic[:]...)
+	b = appendUint32(b, IndexVersion)
+	b = appendUint32(b, uint32(len(paths)))
+	for _, path := range paths {
+		e := ix.entries[path]
+		b = appendUint16(b, uint16(len(path)))
+		b = append(b, path...)
+		b = appendUint64(b, e.Dev)
+		b = appendUint64(b, e.Ino)
+		b = appendUint64(b, uint64(e.Size))
+		var flags byte
+		if e.Racy {
+			flags |= indexRacy
+		}
+		b = appendIndexTimes(b, e.Times, flags)
+	}
+	return appendUint32(b, crc32.ChecksumIEEE(b)), nil
+}
+
+func decodeIndex(b []byte) (*Index, error) {
+	if len(b) < indexHeaderSize+indexTrailerSize || [4]byte{b[0], b[1], b[2], b[3]} != indexMagic {
+		return nil, ErrIndexCorrupt
+	}
+	if v := binary.BigEndian.Uint32(b[4:]); v != IndexVersion {
+		return nil, fmt.Errorf("%w %d", ErrIndexVersion, v)
+	}
+	sum := len(b) - indexTrailerSize
+	if crc32.ChecksumIEEE(b[:sum]) != binary.BigEndian.Uint32(b[sum:]) {
+		return nil, ErrIndexCorrupt
+	}
+	count := binary.BigEndian.Uint32(b[8:])
+	b = b[indexHeaderSize:sum]
+	if uint64(count)*indexEntrySize > uint64(len(b)) {
+		return nil, ErrIndexCorrupt
+	}
+
+	ix := &Index{entries: make(map[string]IndexEntry, count)}
+	for i := uint32(0); i < count; i++ {
+		if len(b) < indexEntrySize {
+			return nil, ErrIndexCorrupt
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < indexEntrySize+n {
+			return nil, ErrIndexCorrupt
+		}
+		e := IndexEntry{Path: string(b[2 : 2+n])}
+		b = b[2+n:]
+		e.Dev = binary.BigEndian.Uint64(b)
+		e.Ino = binary.BigEndian.Uint64(b[8:])
+		e.Size = int64(binary.BigEndian.Uint64(b[16:]))
+		var flags byte
+		e.Times, flags, b = decodeIndexTimes(b[24:], e.Dev, e.Ino, e.Size)
+		e.Racy = flags&indexRacy != 0
+		ix.entries[e.Path] = e
+	}
+	if len(b) != 0 {
+		return nil, ErrIndexCorrupt
+	}
+	return ix, nil
+}
+
+// appendIndexTimes appends the times of t, its source, its flags (with the extra
+// ones) and its statx mask.
+func appendIndexTimes(b []byte, t Times, flags byte) []byte {
+	for _, tt := range []time.Time{t.Atime, t.Mtime, t.Ctime, t.Btime} {
+		b = appendIndexTime(b, tt)
+	}
+	if t.HasCtime {
+		flags |= indexHasCtime
+	}
+	if t.HasBtime {
+		flags |= indexHasBtime
+	}
+	b = append(b, byte(t.Source), flags)
+	return appendUint32(b, t.StatxMask)
+}
+
+// decodeIndexTimes decodes what appendIndexTimes appended, for the file with the
+// given device and inode numbers and size. The flags are returned for the extra ones.
+func decodeIndexTimes(b []byte, dev, ino uint64, size int64) (Times, byte, []byte) {
+	var t Times
+	t.Atime, b = decodeIndexTime(b)
+	t.Mtime, b = decodeIndexTime(b)
+	t.Ctime, b = decodeIndexTime(b)
+	t.Btime, b = decodeIndexTime(b)
+	t.Source = Source(b[0])
+	flags := b[1]
+	t.HasCtime = flags&indexHasCtime != 0
+	t.HasBtime = flags&indexHasBtime != 0
+	t.StatxMask = binary.BigEndian.Uint32(b[2:])
+	t.id = fileID{dev: dev, ino: ino}
+	t.size, t.hasSize = size, true
+	return t, flags, b[6:]
+}
+
+func appendIndexTime(b []byte, t time.Time) []byte {
+	b = appendUint64(b, uint64(t.Unix()))
+	return appendUint32(b, uint32(t.Nanosecond()))
+}
+
+func decodeIndexTime(b []byte) (time.Time, []byte) {
+	t := time.Unix(int64(binary.BigEndian.Uint64(b)), int64(binary.BigEndian.Uint32(b[8:])))
+	if t.IsZero() {
+		t = time.Time{}
+	}
+	return t, b[12:]
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
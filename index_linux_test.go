@@ -0,0 +1,85 @@
+package times
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// indexStatx fakes the statx results of the paths in fake, the other paths are stat'd.
+func indexStatx(fake map[string]*unix.Statx_t) statxFuncTyp {
+	return func(dirfd int, path string, flags int, mask int, stat *unix.Statx_t) error {
+		if st, ok := fake[path]; ok {
+			*stat = *st
+			return nil
+		}
+		return statx(dirfd, path, flags, mask, stat)
+	}
+}
+
+func indexStatxT(t time.Time, ino, size uint64) *unix.Statx_t {
+	st := statxT(t, true)
+	st.Mask |= unix.STATX_INO | unix.STATX_SIZE
+	st.Dev_major, st.Dev_minor = 8, 1
+	st.Ino, st.Size = ino, size
+	return st
+}
+
+func TestIndexStatx(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "index")
+	written := time.Now().Add(-time.Hour).Truncate(time.Second)
+	fake := map[string]*unix.Statx_t{
+		"racy":   indexStatxT(written, 1, 10),
+		"future": indexStatxT(written.Add(time.Hour), 2, 10),
+		"clean":  indexStatxT(written.Add(-1), 3, 10),
+	}
+	restore := setStatx(indexStatx(fake))
+	defer restore()
+
+	ix := NewIndex()
+	for path := range fake {
+		e, err := ix.Update(path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if e.Dev != unix.Mkdev(8, 1) || e.Ino != fake[path].Ino || e.Size != 10 {
+			t.Errorf("got entry %+v", e)
+		}
+	}
+	if err := ix.Save(name); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the index was written at written.
+	if err := os.Chtimes(name, written, written); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	loaded, err := LoadIndex(name)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for path, wantRacy := range map[string]bool{"racy": true, "future": true, "clean": false} {
+		e, ok := loaded.Get(path)
+		if !ok || e.Racy != wantRacy {
+			t.Errorf("%s: got %+v, want racy %v", path, e, wantRacy)
+		}
+		expectClean(t, loaded, path, !wantRacy)
+	}
+
+	// a new inode, or a new size, with the same times.
+	fake["clean"] = indexStatxT(written.Add(-1), 4, 10)
+	expectClean(t, loaded, "clean", false)
+	fake["clean"] = indexStatxT(written.Add(-1), 3, 11)
+	expectClean(t, loaded, "clean", false)
+
+	// a change time which moved.
+	st := indexStatxT(written.Add(-1), 3, 10)
+	st.Ctime = timeToStatx(written.Add(time.Minute))
+	fake["clean"] = st
+	expectClean(t, loaded, "clean", false)
+}
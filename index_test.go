@@ -0,0 +1,221 @@
+package times
+
+import (
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func indexTest(t *testing.T, fn func(dir string, ix *Index, names []string)) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var names []string
+	for _, name := range []string{"a", "b", "c"} {
+		name = filepath.Join(dir, name)
+		if err := ioutil.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Chtimes(name, old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+		names = append(names, name)
+	}
+
+	ix := NewIndex()
+	for _, name := range names {
+		if _, err := ix.Update(name); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	fn(dir, ix, names)
+}
+
+func expectClean(t *testing.T, ix *Index, name string, want bool) {
+	t.Helper()
+	if _, clean, err := ix.Verify(name); err != nil {
+		t.Fatal(err.Error())
+	} else if clean != want {
+		t.Errorf("%s: got clean %v, want %v", name, clean, want)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	indexTest(t, func(dir string, ix *Index, names []string) {
+		name := filepath.Join(dir, "index")
+		if err := ix.Save(name); err != nil {
+			t.Fatal(err.Error())
+		}
+		loaded, err := LoadIndex(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if loaded.Len() != len(names) {
+			t.Fatalf("got %d entries, want %d", loaded.Len(), len(names))
+		}
+		for i, path := range loaded.Paths() {
+			if path != names[i] {
+				t.Errorf("got path %s, want %s", path, names[i])
+			}
+			got, _ := loaded.Get(path)
+			want, _ := ix.Get(path)
+			if got.Dev != want.Dev || got.Ino != want.Ino || got.Size != want.Size || got.Racy ||
+				got.Times.Source != want.Times.Source || got.Times.StatxMask != want.Times.StatxMask || !got.Times.Equal(want.Times) {
+				t.Errorf("got entry %+v, want %+v", got, want)
+			}
+			if got.Size != int64(len(path)) {
+				t.Errorf("got size %d, want %d", got.Size, len(path))
+			}
+			expectClean(t, loaded, path, true)
+		}
+
+		// contents and times changed.
+		if err := ioutil.WriteFile(names[0], []byte("changed"), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		expectClean(t, loaded, names[0], false)
+		at := time.Now().Add(-2 * time.Hour)
+		if err := os.Chtimes(names[1], at, at); err != nil {
+			t.Fatal(err.Error())
+		}
+		expectClean(t, loaded, names[1], false)
+
+		// unknown and removed paths.
+		loaded.Remove(names[2])
+		expectClean(t, loaded, names[2], false)
+		if err := os.Remove(names[2]); err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, _, err := loaded.Verify(names[2]); !os.IsNotExist(err) {
+			t.Errorf("got err %v, want not exist", err)
+		}
+		expectNoTempFiles(t, dir, 3)
+	})
+}
+
+func TestIndexRacy(t *testing.T) {
+	indexTest(t, func(dir string, ix *Index, names []string) {
+		// written right before the index.
+		if err := ioutil.WriteFile(names[0], []byte("racy"), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, err := ix.Update(names[0]); err != nil {
+			t.Fatal(err.Error())
+		}
+		e, _ := ix.Get(names[0])
+
+		name := filepath.Join(dir, "index")
+		if err := ix.Save(name); err != nil {
+			t.Fatal(err.Error())
+		}
+		idx, err := Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if idx.ModTime().After(e.Times.Mtime) {
+			// the clock ticked between the two writes, make it racy.
+			if err := os.Chtimes(name, idx.AccessTime(), e.Times.Mtime); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+
+		loaded, err := LoadIndex(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if e, _ := loaded.Get(names[0]); !e.Racy {
+			t.Error("expected the entry to be racy")
+		}
+		if e, _ := loaded.Get(names[1]); e.Racy {
+			t.Error("expected the entry not to be racy")
+		}
+		expectClean(t, loaded, names[0], false)
+
+		// still racy in an index saved later.
+		later := filepath.Join(dir, "later")
+		if err := loaded.Save(later); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Chtimes(later, time.Now(), time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err.Error())
+		}
+		if loaded, err = LoadIndex(later); err != nil {
+			t.Fatal(err.Error())
+		}
+		if e, _ := loaded.Get(names[0]); !e.Racy {
+			t.Error("expected the entry to stay racy")
+		}
+
+		// once its contents were checked.
+		e, _, err = loaded.Verify(names[0])
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		loaded.Put(e)
+		expectClean(t, loaded, names[0], true)
+	})
+}
+
+func TestIndexCorrupt(t *testing.T) {
+	indexTest(t, func(dir string, ix *Index, names []string) {
+		data, err := ix.encode()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		name := filepath.Join(dir, "index")
+		load := func(b []byte) error {
+			if err := ioutil.WriteFile(name, b, 0644); err != nil {
+				t.Fatal(err.Error())
+			}
+			_, err := LoadIndex(name)
+			return err
+		}
+
+		if err := load(data); err != nil {
+			t.Fatal(err.Error())
+		}
+		version := append([]byte(nil), data...)
+		version[7] = IndexVersion + 1
+		if err := load(version); !errors.Is(err, ErrIndexVersion) {
+			t.Errorf("got err %v, want %v", err, ErrIndexVersion)
+		}
+
+		tests := map[string][]byte{
+			"empty":     nil,
+			"magic":     append([]byte("XXXX"), data[4:]...),
+			"truncated": data[:len(data)-10],
+			"flipped":   append(append(append([]byte(nil), data[:20]...), ^data[20]), data[21:]...),
+			"trailing":  append(append([]byte(nil), data...), 0),
+		}
+		for test, b := range tests {
+			if err := load(b); !errors.Is(err, ErrIndexCorrupt) {
+				t.Errorf("%s: got err %v, want %v", test, err, ErrIndexCorrupt)
+			}
+		}
+	})
+
+	if _, err := LoadIndex(filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+}
+
+func TestDecodeIndexCount(t *testing.T) {
+	data, err := NewIndex().encode()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if ix, err := decodeIndex(data); err != nil || ix.Len() != 0 {
+		t.Fatalf("got %v, %v for an empty index", ix, err)
+	}
+
+	// a count which doesn't match the entries, with a good checksum.
+	b := appendUint32(append([]byte(nil), data[:8]...), 1)
+	b = appendUint32(b, crc32.ChecksumIEEE(b))
+	if _, err := decodeIndex(b); err != ErrIndexCorrupt {
+		t.Errorf("got err %v, want %v", err, ErrIndexCorrupt)
+	}
+}
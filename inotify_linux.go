@@ -0,0 +1,386 @@
+package times
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyWatchMask are the events which may change the times of a path.
+const inotifyWatchMask = unix.IN_ACCESS | unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_CLOSE_WRITE |
+	unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// InotifyWatcher is a Watcher which is told about changes by inotify(7) as they
+// happen, instead of polling. Each inotify event is followed by a statx(2) of the
+// affected path, and the Event carries those times and the ones it had before.
+// If the inotify queue overflows (IN_Q_OVERFLOW) every watched path is stat'd again,
+// so no change is missed, but several changes may be reported as one.
+//
+// Unlike a Watcher, an InotifyWatcher stops watching a path which was added with Add
+// once the path is removed or renamed, even if it comes back later, since inotify
+// watches files, not paths.
+type InotifyWatcher struct {
+	// Events receives the changes, it must be read for the watcher to go on.
+	// It is closed by Close.
+	Events <-chan Event
+
+	// Errors receives the errors of inotify and Stat, except for a path not existing
+	// (which is an Event). It is closed by Close. It need not be read, the errors
+	// are dropped once its buffer is full.
+	Errors <-chan error
+
+	events   chan Event
+	errors   chan error
+	f        *os.File
+	fd       int
+	nofollow bool
+	workers  int
+
+	mu      sync.Mutex
+	roots   map[string]bool // recursive or not
+	watched timesCache
+	wds     map[int]map[inotifyWatch]bool // overlapping roots share the wd of a directory
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type inotifyWatch struct {
+	path, root string
+}
+
+// NewInotifyWatcher returns an InotifyWatcher configured by opts,
+// opts.Interval is not used and opts.Workers is only used to rescan.
+func NewInotifyWatcher(opts WatcherOptions) (*InotifyWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	w := &InotifyWatcher{
+		events:   make(chan Event),
+		errors:   make(chan error, watcherErrorsBuffer),
+		f:        os.NewFile(uintptr(fd), "inotify"),
+		fd:       fd,
+		nofollow: opts.NoFollow,
+		workers:  opts.Workers,
+		roots:    make(map[string]bool),
+		watched:  make(timesCache),
+		wds:      make(map[int]map[inotifyWatch]bool),
+		done:     make(chan struct{}),
+	}
+	if w.workers <= 0 {
+		w.workers = 4
+	}
+	w.Events, w.Errors = w.events, w.errors
+
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Add watches path, which must exist. Changes are reported relative to its times now.
+// For a directory only the times of the directory itself are watched, see AddRecursive,
+// unless it was added with AddRecursive already.
+func (w *InotifyWatcher) Add(path string) error {
+	return w.add(path, false)
+}
+
+// AddRecursive watches the directory dir and every path beneath it,
+// paths created beneath it later are reported and watched too.
+func (w *InotifyWatcher) AddRecursive(dir string) error {
+	return w.add(dir, true)
+}
+
+func (w *InotifyWatcher) add(root string, recursive bool) error {
+	root = filepath.Clean(root)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWatcherClosed
+	}
+
+	// a root added again keeps its watches, and stays recursive.
+	was, added := w.roots[root]
+	recursive = recursive || was
+
+	// the watches are added first, so no change after the times are read is missed.
+	if err := w.addWatch(root, root); err != nil {
+		return err
+	}
+	w.roots[root] = recursive
+	var err error
+	if recursive {
+		_, _, err = w.scanTree(root, root, nil)
+	} else {
+		var t Times
+		if t, err = statPath(root, w.nofollow); err == nil {
+			w.watched.watch(root, root, t)
+		}
+	}
+	if err != nil && !added {
+		w.unwatch(root)
+	}
+	return err
+}
+
+// scanTree watches the directories beneath dir and updates the times of the paths
+// beneath it (and dir itself), which are added to seen if it is not nil.
+// It returns the events and the errors of Stat.
+func (w *InotifyWatcher) scanTree(root, dir string, seen map[string]bool) ([]Event, []error, error) {
+	paths, dirs, err := walkPaths(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var events []Event
+	var errs []error
+	for _, dir := range dirs {
+		if err := w.addWatch(root, dir); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	results := statPaths(paths, w.nofollow, w.workers)
+	for i, path := range paths {
+		if seen != nil {
+			seen[path] = true
+		}
+		e, ok, err := w.watched.update(root, path, results[i].t, results[i].err, 0)
+		if err != nil {
+			errs = append(errs, err)
+		} else if ok {
+			events = append(events, e)
+		}
+	}
+	return events, errs, nil
+}
+
+func (w *InotifyWatcher) addWatch(root, path string) error {
+	mask := uint32(inotifyWatchMask)
+	if w.nofollow {
+		mask |= unix.IN_DONT_FOLLOW
+	}
+	wd, err := unix.InotifyAddWatch(w.fd, path, mask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	if w.wds[wd] == nil {
+		w.wds[wd] = make(map[inotifyWatch]bool)
+	}
+	w.wds[wd][inotifyWatch{path: path, root: root}] = true
+	return nil
+}
+
+// rmWatches forgets the watches matched by fn, and removes the inotify watches
+// no root uses anymore.
+func (w *InotifyWatcher) rmWatches(fn func(watch inotifyWatch) bool) {
+	for wd, watches := range w.wds {
+		for watch := range watches {
+			if fn(watch) {
+				delete(watches, watch)
+			}
+		}
+		if len(watches) == 0 {
+			unix.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.wds, wd)
+		}
+	}
+}
+
+// Remove stops watching path, it must have been added with Add or AddRecursive.
+func (w *InotifyWatcher) Remove(path string) error {
+	path = filepath.Clean(path)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWatcherClosed
+	}
+	if _, ok := w.roots[path]; !ok {
+		return &os.PathError{Op: "remove watch", Path: path, Err: errors.New("not watched")}
+	}
+	w.unwatch(path)
+	return nil
+}
+
+// unwatch forgets root and everything it watches.
+func (w *InotifyWatcher) unwatch(root string) {
+	delete(w.roots, root)
+	w.rmWatches(func(watch inotifyWatch) bool { return watch.root == root })
+	for path := range w.watched {
+		w.watched.unwatch(root, path)
+	}
+}
+
+// Close stops watching and closes Events and Errors.
+func (w *InotifyWatcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	// the fd is closed with mu held, so it isn't used after it may have been reused,
+	// and after done is, so run does not report the error of reading it.
+	w.closed = true
+	close(w.done)
+	err := w.f.Close()
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	close(w.events)
+	close(w.errors)
+	return err
+}
+
+func (w *InotifyWatcher) run() {
+	defer w.wg.Done()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				sendError(w.errors, err)
+			}
+			return
+		}
+
+		events, errs := w.handle(buf[:n])
+		for _, e := range events {
+			select {
+			case w.events <- e:
+			case <-w.done:
+				return
+			}
+		}
+		for _, err := range errs {
+			sendError(w.errors, err)
+		}
+	}
+}
+
+// handle stats the paths affected by the inotify events in buf.
+func (w *InotifyWatcher) handle(buf []byte) ([]Event, []error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, nil
+	}
+
+	var events []Event
+	var errs []error
+	for len(buf) >= unix.SizeofInotifyEvent {
+		raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		end := unix.SizeofInotifyEvent + int(raw.Len)
+		name := string(bytes.TrimRight(buf[unix.SizeofInotifyEvent:end], "\x00"))
+		buf = buf[end:]
+
+		if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+			e, err := w.rescan()
+			events, errs = append(events, e...), append(errs, err...)
+			continue
+		}
+		watches, ok := w.wds[int(raw.Wd)]
+		if !ok {
+			continue
+		}
+		if raw.Mask&unix.IN_IGNORED != 0 {
+			delete(w.wds, int(raw.Wd))
+			continue
+		}
+
+		for watch := range watches {
+			paths := []string{watch.path}
+			if name != "" && w.roots[watch.root] {
+				// an event of a path in a watched directory, which changes the times of
+				// the directory too if the path was created, removed or renamed.
+				paths[0] = filepath.Join(watch.path, name)
+				if raw.Mask&(unix.IN_CREATE|unix.IN_DELETE|unix.IN_MOVED_FROM|unix.IN_MOVED_TO) != 0 {
+					paths = append(paths, watch.path)
+				}
+			}
+			for _, path := range paths {
+				e, err := w.stat(watch.root, path, raw.Mask&unix.IN_ISDIR != 0)
+				events, errs = append(events, e...), append(errs, err...)
+			}
+		}
+	}
+	return events, errs
+}
+
+// stat updates the times of path, watched by root, and returns the events and the errors.
+func (w *InotifyWatcher) stat(root, path string, isDir bool) ([]Event, []error) {
+	if isDir && path != root && w.roots[root] {
+		if _, ok := w.watched[path]; !ok {
+			// a new directory, what was created in it before it was watched is reported too.
+			events, errs, err := w.scanTree(root, path, nil)
+			if err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			if err == nil {
+				return events, errs
+			}
+		}
+	}
+
+	t, err := statPath(path, w.nofollow)
+	e, ok, err := w.watched.update(root, path, t, err, 0)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if !ok {
+		return nil, nil
+	}
+	events := []Event{e}
+	if e.New == nil {
+		// a removed or renamed directory takes the paths beneath it along,
+		// and the watches of a renamed one would report them under the old path.
+		prefix := path + string(filepath.Separator)
+		for p := range w.watched {
+			if strings.HasPrefix(p, prefix) {
+				events = append(events, w.watched.remove(p))
+			}
+		}
+		w.rmWatches(func(watch inotifyWatch) bool {
+			return watch.path == path || strings.HasPrefix(watch.path, prefix)
+		})
+	}
+	return events, nil
+}
+
+// rescan stats every watched path again, after inotify events were lost.
+func (w *InotifyWatcher) rescan() ([]Event, []error) {
+	var events []Event
+	var errs []error
+	seen := make(map[string]bool)
+	for root, recursive := range w.roots {
+		if !recursive {
+			seen[root] = true
+			e, err := w.stat(root, root, false)
+			events, errs = append(events, e...), append(errs, err...)
+			continue
+		}
+		e, errs2, err := w.scanTree(root, root, seen)
+		events, errs = append(events, e...), append(errs, errs2...)
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	for path, wp := range w.watched {
+		if !seen[path] && polled(wp.roots, w.roots) {
+			events = append(events, w.watched.remove(path))
+		}
+	}
+	return events, errs
+}
@@ -0,0 +1,242 @@
+package times
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func newInotifyWatcherTest(t *testing.T) *InotifyWatcher {
+	t.Helper()
+	w, err := NewInotifyWatcher(WatcherOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func nextInotifyEvent(t *testing.T, w *InotifyWatcher) Event {
+	t.Helper()
+	select {
+	case e := <-w.Events:
+		return e
+	case err := <-w.Errors:
+		t.Fatal(err.Error())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return Event{}
+}
+
+func TestInotifyWatcher(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		w := newInotifyWatcherTest(t)
+		old, err := Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := w.Add(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		at := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(f.Name(), at, at); err != nil {
+			t.Fatal(err.Error())
+		}
+		e := nextInotifyEvent(t, w)
+		if want := FieldAtime | FieldMtime | FieldCtime; e.Path != f.Name() || e.Changed != want {
+			t.Fatalf("got event %+v, want %s changed", e, want)
+		}
+		if SourceOf(e.New) != SourceStatx {
+			t.Errorf("got source %s, want %s", SourceOf(e.New), SourceStatx)
+		}
+		timespecTest(e.Old, newInterval(old.ModTime(), 0), t, Timespec.ModTime)
+		timespecTest(e.New, newInterval(at, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+		if err := w.Remove(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := w.Remove(f.Name()); err == nil {
+			t.Error("expected an error removing a path which isn't watched")
+		}
+		if err := os.Chtimes(f.Name(), old.AccessTime(), old.ModTime()); err != nil {
+			t.Fatal(err.Error())
+		}
+		select {
+		case e := <-w.Events:
+			t.Errorf("unexpected event %+v", e)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+func TestInotifyWatcherRecursive(t *testing.T) {
+	dir := t.TempDir()
+	w := newInotifyWatcherTest(t)
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// a directory created with contents is reported along with them.
+	tmp := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(tmp, "file"), nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Rename(tmp, sub); err != nil {
+		t.Fatal(err.Error())
+	}
+	name := filepath.Join(sub, "file")
+	events := make(map[string]Event)
+	for events[name].New == nil || events[sub].New == nil || events[dir].New == nil {
+		e := nextInotifyEvent(t, w)
+		events[e.Path] = e
+	}
+	if events[name].Old != nil || events[sub].Old != nil {
+		t.Errorf("expected created events, got %+v and %+v", events[name], events[sub])
+	}
+	if events[dir].Changed&FieldMtime == 0 {
+		t.Errorf("expected the mtime of %s to change, got %+v", dir, events[dir])
+	}
+
+	// the new directory is watched.
+	at := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, at, at); err != nil {
+		t.Fatal(err.Error())
+	}
+	if e := nextInotifyEvent(t, w); e.Path != name || e.Changed&FieldMtime == 0 {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	// removing it takes its contents along.
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err.Error())
+	}
+	removed := make(map[string]bool)
+	for !removed[name] || !removed[sub] {
+		if e := nextInotifyEvent(t, w); e.New == nil {
+			removed[e.Path] = true
+		}
+	}
+}
+
+func TestInotifyWatcherOverlapping(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	w := newInotifyWatcherTest(t)
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := w.AddRecursive(sub); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// sub is still watched by dir, whose watch of it is shared.
+	if err := w.Remove(sub); err != nil {
+		t.Fatal(err.Error())
+	}
+	name := filepath.Join(sub, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	for {
+		if e := nextInotifyEvent(t, w); e.Path == name {
+			if e.Old != nil || e.New == nil {
+				t.Errorf("got event %+v, want created", e)
+			}
+			break
+		}
+	}
+}
+
+func TestInotifyWatcherAddRecursiveTwice(t *testing.T) {
+	dir := t.TempDir()
+	w := newInotifyWatcherTest(t)
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+	// still recursive.
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	name := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	for {
+		if e := nextInotifyEvent(t, w); e.Path == name {
+			break
+		}
+	}
+}
+
+func TestInotifyWatcherOverflow(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	gone := filepath.Join(dir, "gone")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w := newInotifyWatcherTest(t)
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// changes which were lost.
+	w.mu.Lock()
+	w.watched[name].t.Atime = time.Time{}
+	w.watched.watch(dir, gone, Times{})
+	w.mu.Unlock()
+
+	buf := make([]byte, unix.SizeofInotifyEvent)
+	*(*unix.InotifyEvent)(unsafe.Pointer(&buf[0])) = unix.InotifyEvent{Wd: -1, Mask: unix.IN_Q_OVERFLOW}
+	events, errs := w.handle(buf)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	got := make(map[string]Event)
+	for _, e := range events {
+		got[e.Path] = e
+	}
+	if len(got) != 2 || got[name].Changed != FieldAtime || got[gone].New != nil {
+		t.Errorf("got events %+v, want an atime change and a removal", events)
+	}
+}
+
+func TestInotifyWatcherErr(t *testing.T) {
+	w := newInotifyWatcherTest(t)
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := w.Add(missing); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+	if err := w.AddRecursive(missing); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+	if len(w.wds) != 0 || len(w.roots) != 0 {
+		t.Errorf("expected nothing watched, got %v and %v", w.wds, w.roots)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := <-w.Events; ok {
+		t.Error("expected Events to be closed")
+	}
+	if err, ok := <-w.Errors; ok {
+		t.Errorf("got err %v reading the closed inotify fd", err)
+	}
+	if err := w.Add(t.TempDir()); err != ErrWatcherClosed {
+		t.Errorf("got err %v, want %v", err, ErrWatcherClosed)
+	}
+}
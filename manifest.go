@@ -0,0 +1,403 @@
+package times
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ManifestVersion is the version of the format written by Manifest.Save.
+const ManifestVersion = 1
+
+var (
+	// ErrManifestCorrupt is returned by LoadManifest for a file which is not a
+	// manifest, or which was damaged.
+	ErrManifestCorrupt = errors.New("manifest corrupt")
+
+	// ErrManifestVersion is returned by LoadManifest for a manifest written in a
+	// newer format.
+	ErrManifestVersion = errors.New("unsupported manifest version")
+)
+
+var manifestMagic = [4]byte{'T', 'M', 'A', 'N'}
+
+const (
+	manifestHeaderSize  = 4 + 4 + 12 + 2 // magic, version, taken, root length
+	manifestEntrySize   = 2 + 4 + 3*8 + 4*12 + 1 + 1 + 4
+	manifestTrailerSize = 4 // crc32
+)
+
+// ManifestEntry is what a Manifest records about a path.
+type ManifestEntry struct {
+	// Path is relative to the root of the Manifest, with slashes,
+	// the root itself is ".".
+	Path string
+
+	// Type is the type bits of the mode (fs.ModeDir, fs.ModeSymlink, ...),
+	// zero for a regular file.
+	Type fs.FileMode
+
+	// Dev and Ino are the device and inode numbers, zero if not known.
+	Dev, Ino uint64
+
+	Size  int64
+	Times Times
+}
+
+// Manifest is a snapshot of the times of every path in a tree, it can be saved to a
+// file and diffed against a later snapshot, to audit what changed in between.
+type Manifest struct {
+	// Root is the root of the tree, as it was given to Snapshot.
+	Root string
+
+	// Taken is when the snapshot was taken.
+	Taken time.Time
+
+	// Entries are sorted by Path.
+	Entries []ManifestEntry
+}
+
+// Snapshot returns a Manifest of the tree at root. Everything is stat'd with
+// Lstat, so symlinks are recorded as themselves and are not followed.
+// Paths removed while the tree is walked are left out.
+func Snapshot(root string) (*Manifest, error) {
+	m := &Manifest{Root: root, Taken: time.Now()}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != root {
+				return nil
+			}
+			return err
+		}
+		e := ManifestEntry{Type: d.Type()}
+		if err := stableSample(path, true, &e.Times); err != nil {
+			if os.IsNotExist(err) && path != root {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		e.Path = filepath.ToSlash(rel)
+		e.Dev, e.Ino, e.Size = e.Times.id.dev, e.Times.id.ino, e.Times.size
+		m.Entries = append(m.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// WalkDir walks in lexical order of the names, not of the paths.
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	return m, nil
+}
+
+// Get returns the entry for path, if there is one.
+func (m *Manifest) Get(path string) (ManifestEntry, bool) {
+	i := sort.Search(len(m.Entries), func(i int) bool { return m.Entries[i].Path >= path })
+	if i < len(m.Entries) && m.Entries[i].Path == path {
+		return m.Entries[i], true
+	}
+	return ManifestEntry{}, false
+}
+
+// LoadManifest reads the Manifest saved to name.
+func LoadManifest(name string) (*Manifest, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	m, err := decodeManifest(data)
+	if err != nil {
+		return nil, &os.PathError{Op: "load manifest", Path: name, Err: err}
+	}
+	return m, nil
+}
+
+// Save writes m to name. The file is replaced atomically, so a crash leaves
+// either the old or the new manifest behind, see SyncError.
+func (m *Manifest) Save(name string) error {
+	data, err := m.encode()
+	if err != nil {
+		return err
+	}
+	return replaceFile(name, data, 0644, func(*os.File) error { return nil })
+}
+
+// encode returns m in the ManifestVersion format:
+//
+//	"TMAN" version:uint32 taken:(sec:int64 nsec:uint32) len:uint16 root count:uint32
+//	count * (len:uint16 path type:uint32 dev:uint64 ino:uint64 size:int64
+//	         4 * (sec:int64 nsec:uint32) source:uint8 flags:uint8 statxMask:uint32)
+//	crc32:uint32
+//
+// All the integers are big endian, the times are atime, mtime, ctime and btime.
+func (m *Manifest) encode() ([]byte, error) {
+	if len(m.Root) > math.MaxUint16 {
+		return nil, &os.PathError{Op: "save manifest", Path: m.Root, Err: errors.New("path too long")}
+	}
+	size := manifestHeaderSize + len(m.Root) + 4 + manifestTrailerSize + len(m.Entries)*manifestEntrySize
+	for _, e := range m.Entries {
+		if len(e.Path) > math.MaxUint16 {
+			return nil, &os.PathError{Op: "save manifest", Path: e.Path, Err: errors.New("path too long")}
+		}
+		size += len(e.Path)
+	}
+
+	b := make([]byte, 0, size)
+	b = append(b, manifestMagic[:]...)
+	b = appendUint32(b, ManifestVersion)
+	b = appendIndexTime(b, m.Taken)
+	b = appendUint16(b, uint16(len(m.Root)))
+	b = append(b, m.Root...)
+	b = appendUint32(b, uint32(len(m.Entries)))
+	for _, e := range m.Entries {
+		b = appendUint16(b, uint16(len(e.Path)))
+		b = append(b, e.Path...)
+		b = appendUint32(b, uint32(e.Type))
+		b = appendUint64(b, e.Dev)
+		b = appendUint64(b, e.Ino)
+		b = appendUint64(b, uint64(e.Size))
+		b = appendIndexTimes(b, e.Times, 0)
+	}
+	return appendUint32(b, crc32.ChecksumIEEE(b)), nil
+}
+
+func decodeManifest(b []byte) (*Manifest, error) {
+	if len(b) < manifestHeaderSize+4+manifestTrailerSize || [4]byte{b[0], b[1], b[2], b[3]} != manifestMagic {
+		return nil, ErrManifestCorrupt
+	}
+	if v := binary.BigEndian.Uint32(b[4:]); v != ManifestVersion {
+		return nil, fmt.Errorf("%w %d", ErrManifestVersion, v)
+	}
+	sum := len(b) - manifestTrailerSize
+	if crc32.ChecksumIEEE(b[:sum]) != binary.BigEndian.Uint32(b[sum:]) {
+		return nil, ErrManifestCorrupt
+	}
+	b = b[:sum]
+
+	m := &Manifest{}
+	m.Taken, b = decodeIndexTime(b[8:])
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n+4 {
+		return nil, ErrManifestCorrupt
+	}
+	m.Root = string(b[2 : 2+n])
+	count := binary.BigEndian.Uint32(b[2+n:])
+	b = b[2+n+4:]
+	if uint64(count)*manifestEntrySize > uint64(len(b)) {
+		return nil, ErrManifestCorrupt
+	}
+
+	m.Entries = make([]ManifestEntry, 0, count)
+	for i := uint32(0); i < count; i++ {
+		if len(b) < manifestEntrySize {
+			return nil, ErrManifestCorrupt
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < manifestEntrySize+n {
+			return nil, ErrManifestCorrupt
+		}
+		e := ManifestEntry{Path: string(b[2 : 2+n])}
+		b = b[2+n:]
+		e.Type = fs.FileMode(binary.BigEndian.Uint32(b))
+		e.Dev = binary.BigEndian.Uint64(b[4:])
+		e.Ino = binary.BigEndian.Uint64(b[12:])
+		e.Size = int64(binary.BigEndian.Uint64(b[20:]))
+		e.Times, _, b = decodeIndexTimes(b[28:], e.Dev, e.Ino, e.Size)
+		if i > 0 && m.Entries[i-1].Path >= e.Path {
+			return nil, ErrManifestCorrupt
+		}
+		m.Entries = append(m.Entries, e)
+	}
+	if len(b) != 0 {
+		return nil, ErrManifestCorrupt
+	}
+	return m, nil
+}
+
+// ChangeKind is the kind of a ManifestChange.
+type ChangeKind int
+
+const (
+	// ChangeAdded means the path is only in the new Manifest.
+	ChangeAdded ChangeKind = iota
+
+	// ChangeRemoved means the path is only in the old Manifest.
+	ChangeRemoved
+
+	// ChangeRenamed means the file at OldPath in the old Manifest is at Path in the new one.
+	ChangeRenamed
+
+	// ChangeModified means the times, the size or the type of the path changed, or it was
+	// replaced by another file.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeRenamed:
+		return "renamed"
+	case ChangeModified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// ManifestChange is a difference between two Manifests.
+type ManifestChange struct {
+	Kind ChangeKind
+
+	// Path is the path in the new Manifest, or in the old one if it is ChangeRemoved.
+	// OldPath is the path in the old Manifest, it differs from Path if it is ChangeRenamed.
+	Path, OldPath string
+
+	// Old and New are the entries in the old and the new Manifest,
+	// Old is nil if the path is ChangeAdded and New is nil if it is ChangeRemoved.
+	Old, New *ManifestEntry
+
+	// Changed is the times which changed, for ChangeRenamed and ChangeModified.
+	Changed Field
+}
+
+// DiffManifests returns the changes from old to new, sorted by Path. The times in
+// ignore are not compared: a tree is usually read between its snapshots, which
+// updates the access times of its directories.
+//
+// A file moved within the tree is ChangeRenamed if it kept its device and inode
+// numbers, and its birth time where both Manifests have one, otherwise it is
+// ChangeRemoved from its old path and ChangeAdded at its new one. A rename changes
+// the change time of the file.
+func DiffManifests(old, new *Manifest, ignore Field) []ManifestChange {
+	oldByPath := make(map[string]*ManifestEntry, len(old.Entries))
+	for i := range old.Entries {
+		oldByPath[old.Entries[i].Path] = &old.Entries[i]
+	}
+	newByPath := make(map[string]*ManifestEntry, len(new.Entries))
+	for i := range new.Entries {
+		newByPath[new.Entries[i].Path] = &new.Entries[i]
+	}
+
+	var changes []ManifestChange
+
+	// the paths whose file is gone, or was replaced by another one, may have been
+	// renamed to one of the paths whose file is new.
+	var removed, added []*ManifestEntry
+	for i := range old.Entries {
+		o := &old.Entries[i]
+		if n, ok := newByPath[o.Path]; !ok || !sameManifestFile(o, n) {
+			removed = append(removed, o)
+		}
+	}
+	for i := range new.Entries {
+		n := &new.Entries[i]
+		o, ok := oldByPath[n.Path]
+		if !ok || !sameManifestFile(o, n) {
+			added = append(added, n)
+			continue
+		}
+		if c, ok := diffManifestEntries(o, n, ignore); ok {
+			changes = append(changes, c)
+		}
+	}
+
+	renamed := make(map[*ManifestEntry]bool)
+	byID := make(map[fileID][]*ManifestEntry)
+	for _, o := range removed {
+		if o.Ino != 0 {
+			id := fileID{dev: o.Dev, ino: o.Ino}
+			byID[id] = append(byID[id], o)
+		}
+	}
+	for _, n := range added {
+		id := fileID{dev: n.Dev, ino: n.Ino}
+		for i, o := range byID[id] {
+			if !sameManifestBtime(o, n) || o.Type != n.Type {
+				continue
+			}
+			byID[id] = append(byID[id][:i], byID[id][i+1:]...)
+			renamed[o], renamed[n] = true, true
+			c, _ := diffManifestEntries(o, n, ignore)
+			c.Kind = ChangeRenamed
+			changes = append(changes, c)
+			break
+		}
+	}
+
+	for _, o := range removed {
+		if renamed[o] {
+			continue
+		}
+		if n, ok := newByPath[o.Path]; ok && !renamed[n] {
+			// replaced by another file, which is not one which was renamed.
+			c, _ := diffManifestEntries(o, n, ignore)
+			changes = append(changes, c)
+			continue
+		}
+		changes = append(changes, ManifestChange{Kind: ChangeRemoved, Path: o.Path, OldPath: o.Path, Old: o})
+	}
+	for _, n := range added {
+		if renamed[n] {
+			continue
+		}
+		if o, ok := oldByPath[n.Path]; ok && !renamed[o] {
+			// reported as Modified with the removed entries.
+			continue
+		}
+		changes = append(changes, ManifestChange{Kind: ChangeAdded, Path: n.Path, New: n})
+	}
+
+	// a path renamed over another file is both removed and renamed, in that order.
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Path != changes[j].Path {
+			return changes[i].Path < changes[j].Path
+		}
+		return changes[i].Kind == ChangeRemoved && changes[j].Kind != ChangeRemoved
+	})
+	return changes
+}
+
+// DiffTree takes a new Snapshot of the root of m and returns the changes since m,
+// see DiffManifests.
+func (m *Manifest) DiffTree(ignore Field) ([]ManifestChange, error) {
+	cur, err := Snapshot(m.Root)
+	if err != nil {
+		return nil, err
+	}
+	return DiffManifests(m, cur, ignore), nil
+}
+
+// diffManifestEntries returns the ChangeModified change from o to n, and whether
+// there is one.
+func diffManifestEntries(o, n *ManifestEntry, ignore Field) (ManifestChange, bool) {
+	c := ManifestChange{
+		Kind:    ChangeModified,
+		Path:    n.Path,
+		OldPath: o.Path,
+		Old:     o,
+		New:     n,
+		Changed: changedFields(o.Times, n.Times) &^ ignore,
+	}
+	return c, c.Changed != 0 || o.Size != n.Size || o.Type != n.Type || !sameManifestFile(o, n)
+}
+
+// sameManifestFile reports whether o and n are the same file, if their inodes are known.
+func sameManifestFile(o, n *ManifestEntry) bool {
+	return o.Ino == 0 || n.Ino == 0 || o.Dev == n.Dev && o.Ino == n.Ino && sameManifestBtime(o, n)
+}
+
+func sameManifestBtime(o, n *ManifestEntry) bool {
+	return !o.Times.HasBtime || !n.Times.HasBtime || o.Times.Btime.Equal(n.Times.Btime)
+}
@@ -0,0 +1,214 @@
+package times
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func manifestTest(t *testing.T, fn func(dir string, m *Manifest)) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, name := range []string{"a", "removed", filepath.Join("sub", "b")} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if err := os.Symlink("a", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	m, err := Snapshot(dir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	fn(dir, m)
+}
+
+func TestManifest(t *testing.T) {
+	manifestTest(t, func(dir string, m *Manifest) {
+		var paths []string
+		for _, e := range m.Entries {
+			paths = append(paths, e.Path)
+		}
+		if want := []string{".", "a", "link", "removed", "sub", "sub/b"}; !reflect.DeepEqual(paths, want) {
+			t.Fatalf("got paths %v, want %v", paths, want)
+		}
+
+		if e, _ := m.Get("."); e.Type != fs.ModeDir {
+			t.Errorf("got type %v for the root, want %v", e.Type, fs.ModeDir)
+		}
+		if e, _ := m.Get("sub/b"); e.Type != 0 || e.Size != int64(len(filepath.Join("sub", "b"))) {
+			t.Errorf("got type %v and size %d for a file", e.Type, e.Size)
+		}
+		// recorded as itself, not as the file it points to.
+		link, _ := m.Get("link")
+		a, _ := m.Get("a")
+		if link.Type != fs.ModeSymlink || link.Size != int64(len("a")) {
+			t.Errorf("got type %v and size %d for a symlink", link.Type, link.Size)
+		}
+		if a.Ino != 0 && link.Ino == a.Ino {
+			t.Error("expected the symlink not to be followed")
+		}
+		if _, ok := m.Get("missing"); ok {
+			t.Error("expected no entry for a missing path")
+		}
+
+		name := filepath.Join(t.TempDir(), "manifest")
+		if err := m.Save(name); err != nil {
+			t.Fatal(err.Error())
+		}
+		loaded, err := LoadManifest(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if loaded.Root != m.Root || !loaded.Taken.Equal(m.Taken) || len(loaded.Entries) != len(m.Entries) {
+			t.Fatalf("got manifest of %s taken at %v with %d entries, want %s at %v with %d",
+				loaded.Root, loaded.Taken, len(loaded.Entries), m.Root, m.Taken, len(m.Entries))
+		}
+		for i, got := range loaded.Entries {
+			want := m.Entries[i]
+			if got.Path != want.Path || got.Type != want.Type || got.Dev != want.Dev || got.Ino != want.Ino ||
+				got.Size != want.Size || got.Times.Source != want.Times.Source || !got.Times.Equal(want.Times) {
+				t.Errorf("got entry %+v, want %+v", got, want)
+			}
+		}
+		if changes := DiffManifests(m, loaded, 0); len(changes) != 0 {
+			t.Errorf("got changes %+v, want none", changes)
+		}
+	})
+}
+
+func TestDiffManifests(t *testing.T) {
+	manifestTest(t, func(dir string, m *Manifest) {
+		old := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(filepath.Join(dir, "a"), old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Rename(filepath.Join(dir, "sub", "b"), filepath.Join(dir, "c")); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Remove(filepath.Join(dir, "removed")); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "added"), nil, 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		changes, err := m.DiffTree(FieldAtime)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		got := make(map[string]ManifestChange)
+		for _, c := range changes {
+			got[c.Path] = c
+		}
+
+		if c := got["a"]; c.Kind != ChangeModified || c.Changed&FieldMtime == 0 || c.Changed&FieldAtime != 0 ||
+			c.Old == nil || c.New == nil || !c.New.Times.Mtime.Equal(old) {
+			t.Errorf("got change %+v for a, want its mtime modified", c)
+		}
+		if c := got["added"]; c.Kind != ChangeAdded || c.Old != nil || c.New == nil {
+			t.Errorf("got change %+v for added, want added", c)
+		}
+		if c := got["removed"]; c.Kind != ChangeRemoved || c.Old == nil || c.New != nil {
+			t.Errorf("got change %+v for removed, want removed", c)
+		}
+		// the directories whose entries changed.
+		for _, path := range []string{".", "sub"} {
+			if c := got[path]; c.Kind != ChangeModified || c.Changed&FieldMtime == 0 {
+				t.Errorf("got change %+v for %s, want its mtime modified", c, path)
+			}
+		}
+		if _, ok := got["link"]; ok {
+			t.Error("expected no change for link")
+		}
+
+		want := 6
+		if b, _ := m.Get("sub/b"); b.Ino == 0 {
+			// no inode numbers to match the renames.
+			if got["c"].Kind != ChangeAdded || got["sub/b"].Kind != ChangeRemoved {
+				t.Errorf("got changes %+v and %+v for the rename", got["c"], got["sub/b"])
+			}
+			want++
+		} else if c := got["c"]; c.Kind != ChangeRenamed || c.OldPath != "sub/b" || c.Changed&FieldMtime != 0 {
+			t.Errorf("got change %+v for c, want renamed from sub/b", c)
+		}
+		if len(changes) != want {
+			t.Errorf("got %d changes, want %d: %+v", len(changes), want, changes)
+		}
+	})
+}
+
+func TestDiffManifestsReplaced(t *testing.T) {
+	manifestTest(t, func(dir string, m *Manifest) {
+		// sub/b is renamed over a, whose file is gone.
+		if err := os.Rename(filepath.Join(dir, "sub", "b"), filepath.Join(dir, "a")); err != nil {
+			t.Fatal(err.Error())
+		}
+		changes, err := m.DiffTree(FieldAtime)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if b, _ := m.Get("sub/b"); b.Ino == 0 {
+			t.Skip("no inode numbers to match the renames")
+		}
+
+		var got []ManifestChange
+		for _, c := range changes {
+			if c.Path == "a" || c.Path == "sub/b" {
+				got = append(got, c)
+			}
+		}
+		if len(got) != 2 || got[0].Kind != ChangeRemoved || got[0].Path != "a" ||
+			got[1].Kind != ChangeRenamed || got[1].Path != "a" || got[1].OldPath != "sub/b" {
+			t.Errorf("got changes %+v, want a removed then sub/b renamed to it", got)
+		}
+	})
+}
+
+func TestManifestCorrupt(t *testing.T) {
+	manifestTest(t, func(dir string, m *Manifest) {
+		data, err := m.encode()
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		name := filepath.Join(t.TempDir(), "manifest")
+		load := func(b []byte) error {
+			if err := ioutil.WriteFile(name, b, 0644); err != nil {
+				t.Fatal(err.Error())
+			}
+			_, err := LoadManifest(name)
+			return err
+		}
+
+		if err := load(data); err != nil {
+			t.Fatal(err.Error())
+		}
+		version := append([]byte(nil), data...)
+		version[7] = ManifestVersion + 1
+		if err := load(version); !errors.Is(err, ErrManifestVersion) {
+			t.Errorf("got err %v, want %v", err, ErrManifestVersion)
+		}
+
+		tests := map[string][]byte{
+			"empty":     nil,
+			"magic":     append([]byte("XXXX"), data[4:]...),
+			"truncated": data[:len(data)-10],
+			"flipped":   append(append(append([]byte(nil), data[:30]...), ^data[30]), data[31:]...),
+			"trailing":  append(append([]byte(nil), data...), 0),
+		}
+		for test, b := range tests {
+			if err := load(b); !errors.Is(err, ErrManifestCorrupt) {
+				t.Errorf("%s: got err %v, want %v", test, err, ErrManifestCorrupt)
+			}
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package times
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var rename = os.Rename
+
+// Move renames src to dst like os.Rename. When they are on different devices
+// (EXDEV), it copies src to dst instead, file by file, and then removes src.
+// The copy restores the access and modification times of every file, directory
+// and symlink from an Lstat taken before it was copied, a directory's times are
+// restored after its children so copying them doesn't change its times.
+// Only linux can set the times of a symlink, elsewhere symlinks get new times.
+//
+// If the copy fails, the partial copy at dst is left in place and src is untouched.
+// Only regular files, directories and symlinks can be copied.
+func Move(src, dst string) error {
+	err := rename(src, dst)
+	if err == nil || !errors.Is(err, errCrossDevice) {
+		return err
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+func copyTree(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	// read the times before reading the contents changes the access time.
+	ts, err := Lstat(src)
+	if err != nil {
+		return err
+	}
+	c := Change{Atime: At(ts.AccessTime()), Mtime: At(ts.ModTime())}
+
+	switch mode := fi.Mode(); {
+	case mode.IsRegular():
+		_, err := CopyFile(src, dst, CopyOptions{Fields: FieldAtime | FieldMtime})
+		return err
+
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, dst); err != nil {
+			return err
+		}
+		if _, err := Lchtimes(dst, c); err != nil && !errors.Is(err, ErrUnsupported) {
+			return err
+		}
+		return nil
+
+	case mode.IsDir():
+		// keep dst writable until its children are copied.
+		if err := os.Mkdir(dst, mode.Perm()|0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := copyTree(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())); err != nil {
+				return err
+			}
+		}
+		if err := os.Chmod(dst, mode.Perm()); err != nil {
+			return err
+		}
+		_, err = Chtimes(dst, c)
+		return err
+	}
+
+	return &os.LinkError{Op: "move", Old: src, New: dst, Err: ErrUnsupported}
+}
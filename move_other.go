@@ -0,0 +1,9 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package times
+
+import "syscall"
+
+// errCrossDevice is the error os.Rename returns when src and dst are on different devices.
+var errCrossDevice error = syscall.EXDEV
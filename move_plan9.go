@@ -0,0 +1,7 @@
+package times
+
+import "errors"
+
+// errCrossDevice is never returned by os.Rename on plan9, which can only rename
+// within a directory, so Move is the same as os.Rename there.
+var errCrossDevice = errors.New("cross-device link")
@@ -0,0 +1,158 @@
+package times
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func crossDeviceRename(src, dst string) error {
+	return &os.LinkError{Op: "rename", Old: src, New: dst, Err: errCrossDevice}
+}
+
+func setRename(fn func(string, string) error) func() {
+	restore := rename
+	rename = fn
+	return func() { rename = restore }
+}
+
+// moveTree creates a tree of files, dirs and symlinks with old times under dir/src.
+func moveTree(t *testing.T, dir string) (src string, old time.Time) {
+	src = filepath.Join(dir, "src")
+	old = time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for _, d := range []string{"", "sub", filepath.Join("sub", "deeper")} {
+		if err := os.Mkdir(filepath.Join(src, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"file", filepath.Join("sub", "file"), filepath.Join("sub", "deeper", "file")} {
+		if err := ioutil.WriteFile(filepath.Join(src, f), []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("file", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// set the times bottom up, so setting them doesn't change them.
+	for _, p := range []string{"link", filepath.Join("sub", "deeper", "file"), filepath.Join("sub", "deeper"), filepath.Join("sub", "file"), "sub", "file", ""} {
+		if _, err := Lchtimes(filepath.Join(src, p), Change{Atime: At(old), Mtime: At(old)}); err != nil && !errors.Is(err, ErrUnsupported) {
+			t.Fatal(err)
+		}
+	}
+	return src, old
+}
+
+func TestMoveCrossDevice(t *testing.T) {
+	restore := setRename(crossDeviceRename)
+	defer restore()
+
+	dir := t.TempDir()
+	src, old := moveTree(t, dir)
+	dst := filepath.Join(dir, "dst")
+
+	if err := Move(src, dst); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := os.Lstat(src); !os.IsNotExist(err) {
+		t.Errorf("expected src to be removed, got %v", err)
+	}
+
+	for _, p := range []string{"", "sub", filepath.Join("sub", "deeper"), "file", filepath.Join("sub", "file"), filepath.Join("sub", "deeper", "file")} {
+		ts, err := Lstat(filepath.Join(dst, p))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !ts.ModTime().Equal(old) || !ts.AccessTime().Equal(old) {
+			t.Errorf("%q: got atime %v and mtime %v, want %v", p, ts.AccessTime(), ts.ModTime(), old)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dst, "sub", "deeper", "file"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if string(data) != filepath.Join("sub", "deeper", "file") {
+		t.Errorf("got contents %q", data)
+	}
+
+	target, err := os.Readlink(filepath.Join(dst, "link"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if target != "file" {
+		t.Errorf("got link target %q, want %q", target, "file")
+	}
+	if runtime.GOOS == "linux" {
+		ts, err := Lstat(filepath.Join(dst, "link"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !ts.ModTime().Equal(old) {
+			t.Errorf("link: got mtime %v, want %v", ts.ModTime(), old)
+		}
+	}
+}
+
+func TestMoveCrossDeviceFile(t *testing.T) {
+	restore := setRename(crossDeviceRename)
+	defer restore()
+
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		dst := filepath.Join(dir, "dst")
+		if err := Move(src, dst); err != nil {
+			t.Fatal(err.Error())
+		}
+		ts, err := Stat(dst)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.AccessTime, Timespec.ModTime)
+		if _, err := os.Lstat(src); !os.IsNotExist(err) {
+			t.Errorf("expected src to be removed, got %v", err)
+		}
+	})
+}
+
+func TestMoveRename(t *testing.T) {
+	oldFileTest(t, func(dir, src string, old time.Time) {
+		dst := filepath.Join(dir, "dst")
+		if err := Move(src, dst); err != nil {
+			t.Fatal(err.Error())
+		}
+		ts, err := Stat(dst)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.ModTime)
+	})
+}
+
+func TestMoveErr(t *testing.T) {
+	dir := t.TempDir()
+	if err := Move(filepath.Join(dir, "missing"), filepath.Join(dir, "dst")); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+
+	restore := setRename(crossDeviceRename)
+	defer restore()
+
+	if err := Move(filepath.Join(dir, "missing"), filepath.Join(dir, "dst")); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+
+	// a failed copy leaves src in place.
+	src, _ := moveTree(t, dir)
+	if err := Move(src, filepath.Join(dir, "missing", "dst")); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "sub", "file")); err != nil {
+		t.Errorf("expected src to be left in place, got %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+package times
+
+import "syscall"
+
+// errCrossDevice is the error os.Rename returns when src and dst are on different devices.
+var errCrossDevice error = syscall.Errno(17) // ERROR_NOT_SAME_DEVICE
@@ -0,0 +1,80 @@
+package times
+
+// Source identifies how the times in a Timespec were obtained.
+type Source uint8
+
+const (
+	// SourceUnknown is used for Timespecs which were not created by this package.
+	SourceUnknown Source = iota
+
+	// SourceStatx means the times came from the linux statx(2) syscall,
+	// Times.StatxMask holds the mask it returned.
+	SourceStatx
+
+	// SourceStat means the times came from stat(2), lstat(2) or fstat(2)
+	// via os.Stat, os.Lstat or (*os.File).Stat.
+	SourceStat
+
+	// SourceFileInfo means the times came from an os.FileInfo passed to Get.
+	SourceFileInfo
+
+	// SourceFileHandle means the times came from GetFileInformationByHandleEx on windows.
+	SourceFileHandle
+
+	// SourceModTime means only the ModTime of an os.FileInfo passed to Get was available,
+	// because its Sys() value was not created by the os package.
+	SourceModTime
+)
+
+var sourceNames = [...]string{
+	SourceUnknown:    "unknown",
+	SourceStatx:      "statx",
+	SourceStat:       "stat",
+	SourceFileInfo:   "FileInfo",
+	SourceFileHandle: "GetFileInformationByHandleEx",
+	SourceModTime:    "ModTime",
+}
+
+func (s Source) String() string {
+	if int(s) < len(sourceNames) {
+		return sourceNames[s]
+	}
+	return "unknown"
+}
+
+// SourceOf returns the Source of ts, or SourceUnknown if ts is not a Times.
+// All Timespecs returned by this package are Times.
+func SourceOf(ts Timespec) Source {
+	if t, ok := ts.(Times); ok {
+		return t.Source
+	}
+	if t, ok := ts.(*Times); ok && t != nil {
+		return t.Source
+	}
+	return SourceUnknown
+}
+
+// Features describes how this package gets times on the running platform.
+type Features struct {
+	// HasChangeTime and HasBirthTime are the platform constants of the same name.
+	HasChangeTime bool
+	HasBirthTime  bool
+
+	// Statx is true if Stat, Lstat and StatFile use the linux statx(2) syscall.
+	Statx bool
+
+	// StatxFallback is true once the kernel has reported that statx(2) is not
+	// implemented (linux 4.10 and earlier), from then on Stat, Lstat and StatFile
+	// use stat(2) which never returns birth times.
+	StatxFallback bool
+}
+
+// SupportedFeatures returns the Features of the running platform.
+func SupportedFeatures() Features {
+	f := Features{
+		HasChangeTime: HasChangeTime,
+		HasBirthTime:  HasBirthTime,
+	}
+	f.Statx, f.StatxFallback = statxState()
+	return f
+}
@@ -0,0 +1,84 @@
+package times
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSourceOf(t *testing.T) {
+	fileAndDirTest(t, func(name string) {
+		ts, err := Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		switch src := SourceOf(ts); src {
+		case SourceStatx, SourceStat, SourceFileHandle:
+		default:
+			t.Errorf("unexpected Stat() source %s", src)
+		}
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if src := SourceOf(Get(fi)); src != SourceFileInfo {
+			t.Errorf("SourceOf(Get()) = %s, want %s", src, SourceFileInfo)
+		}
+	})
+}
+
+func TestSourceOfUnknown(t *testing.T) {
+	var ts struct {
+		atime
+		mtime
+		noctime
+		nobtime
+	}
+	if src := SourceOf(ts); src != SourceUnknown {
+		t.Errorf("SourceOf() = %s, want %s", src, SourceUnknown)
+	}
+
+	tp := &Times{Source: SourceStat}
+	if src := SourceOf(tp); src != SourceStat {
+		t.Errorf("SourceOf(*Times) = %s, want %s", src, SourceStat)
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	tests := map[Source]string{
+		SourceUnknown:    "unknown",
+		SourceStatx:      "statx",
+		SourceStat:       "stat",
+		SourceFileInfo:   "FileInfo",
+		SourceFileHandle: "GetFileInformationByHandleEx",
+		SourceModTime:    "ModTime",
+		Source(200):      "unknown",
+	}
+	for src, want := range tests {
+		if got := src.String(); got != want {
+			t.Errorf("Source(%d).String() = %q, want %q", src, got, want)
+		}
+	}
+}
+
+func TestSupportedFeatures(t *testing.T) {
+	f := SupportedFeatures()
+	if f.HasChangeTime != HasChangeTime || f.HasBirthTime != HasBirthTime {
+		t.Errorf("SupportedFeatures() = %+v, does not match platform constants", f)
+	}
+	if f.Statx && f.StatxFallback {
+		t.Errorf("SupportedFeatures() = %+v, statx can't be used after falling back", f)
+	}
+
+	fileTest(t, func(f *os.File) {
+		ts, err := StatFile(f)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+		if SupportedFeatures().Statx != (SourceOf(ts) == SourceStatx) {
+			t.Errorf("SupportedFeatures().Statx does not match source %s", SourceOf(ts))
+		}
+	})
+}
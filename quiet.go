@@ -0,0 +1,181 @@
+package times
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WaitUntilQuiet waits until the modification time and the size of the file at path
+// have not changed for quiet, then returns its times. A file whose modification time
+// is already older than quiet is quiet right away. It returns ctx.Err() if ctx is done
+// first, or the error of a failed Stat (e.g. the file was removed).
+//
+// On linux the directory of path is watched with inotify (IN_CLOSE_WRITE, IN_ATTRIB, ...)
+// so finished writes are noticed without polling, elsewhere, or if inotify is not
+// available, the file is polled with Stat.
+func WaitUntilQuiet(ctx context.Context, path string, quiet time.Duration) (Timespec, error) {
+	w := newWaker(ctx, filepath.Dir(path), pollInterval(quiet))
+	defer w.close()
+
+	var q quietFile
+	for {
+		var cur Times
+		if err := stableSample(path, false, &cur); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		q.update(cur, now)
+		if left := q.left(quiet, now); left > 0 {
+			if err := w.wait(ctx, left); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return cur, nil
+	}
+}
+
+// WaitDirQuiet watches the regular files in dir (not its subdirectories) and calls fn
+// with the path and the times of each file once it is quiet, like WaitUntilQuiet.
+// A file is passed to fn again if it changes after that and becomes quiet again.
+// It runs until ctx is done, and returns ctx.Err(), or until fn or reading dir fails,
+// and returns that error.
+func WaitDirQuiet(ctx context.Context, dir string, quiet time.Duration, fn func(path string, ts Timespec) error) error {
+	w := newWaker(ctx, dir, pollInterval(quiet))
+	defer w.close()
+
+	files := make(map[string]*quietFile)
+	for {
+		entries, err := readDirTimes(dir)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		wait := time.Duration(-1)
+		seen := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			name := e.Name()
+			seen[name] = true
+			q := files[name]
+			if q == nil {
+				q = &quietFile{}
+				files[name] = q
+			}
+			q.update(e.Times, now)
+			if q.done {
+				continue
+			}
+			if left := q.left(quiet, now); left > 0 {
+				if wait < 0 || left < wait {
+					wait = left
+				}
+				continue
+			}
+			q.done = true
+			if err := fn(filepath.Join(dir, name), e.Times); err != nil {
+				return err
+			}
+		}
+		for name := range files {
+			if !seen[name] {
+				delete(files, name)
+			}
+		}
+
+		if err := w.wait(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func readDirTimes(dir string) ([]DirEntry, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadDirTimes(f)
+}
+
+// quietFile tracks since when a file has not changed.
+type quietFile struct {
+	last  Times
+	since time.Time
+	seen  bool
+
+	// done is set once the file was reported quiet, until it changes again.
+	done bool
+}
+
+// update records the sample cur of the file, taken at now.
+func (q *quietFile) update(cur Times, now time.Time) {
+	switch {
+	case !q.seen:
+		// the modification time says how long the file has been quiet, unless it is
+		// in the future.
+		q.since = cur.Mtime
+		if q.since.After(now) {
+			q.since = now
+		}
+	case !sameSample(q.last, cur):
+		// the file changed since the last sample, which was taken before now.
+		q.since = now
+		if cur.Mtime.After(q.last.Mtime) && cur.Mtime.Before(now) {
+			q.since = cur.Mtime
+		}
+		q.done = false
+	}
+	q.last, q.seen = cur, true
+}
+
+// left returns how much longer the file must stay unchanged to be quiet.
+func (q *quietFile) left(quiet time.Duration, now time.Time) time.Duration {
+	return quiet - now.Sub(q.since)
+}
+
+// pollInterval is how often files which must be quiet for quiet are polled.
+func pollInterval(quiet time.Duration) time.Duration {
+	d := quiet / 4
+	switch {
+	case d < time.Millisecond:
+		return time.Millisecond
+	case d > time.Second:
+		return time.Second
+	}
+	return d
+}
+
+// waker wakes up a wait for quiet files early, when they may have changed.
+type waker interface {
+	// wait waits for d (forever if d < 0), or until a file may have changed.
+	// It returns ctx.Err() if ctx is done first.
+	wait(ctx context.Context, d time.Duration) error
+	close() error
+}
+
+// pollWaker never knows when a file changed, it wakes up every interval.
+type pollWaker struct {
+	interval time.Duration
+}
+
+func (p pollWaker) wait(ctx context.Context, d time.Duration) error {
+	if d < 0 || d > p.interval {
+		d = p.interval
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (p pollWaker) close() error { return nil }
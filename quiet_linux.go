@@ -0,0 +1,85 @@
+package times
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyQuietMask are the events which may end, or restart, the quiet time of a file.
+// Writes themselves (IN_MODIFY) are not watched, they are seen when the quiet time is up.
+const inotifyQuietMask = unix.IN_CLOSE_WRITE | unix.IN_ATTRIB | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// newWaker watches dir with inotify, it falls back to polling every interval if that fails.
+func newWaker(ctx context.Context, dir string, interval time.Duration) waker {
+	w, err := newInotifyWaker(ctx, dir)
+	if err != nil {
+		return pollWaker{interval: interval}
+	}
+	return w
+}
+
+type inotifyWaker struct {
+	f    *os.File
+	buf  []byte
+	stop chan struct{}
+}
+
+func newInotifyWaker(ctx context.Context, dir string) (*inotifyWaker, error) {
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := unix.InotifyAddWatch(fd, dir, inotifyQuietMask); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	// the non-blocking fd is added to the runtime poller, so reads can time out
+	// and be interrupted by closing the file.
+	w := &inotifyWaker{
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		buf:  make([]byte, 4096),
+		stop: make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.f.Close()
+		case <-w.stop:
+		}
+	}()
+	return w, nil
+}
+
+func (w *inotifyWaker) wait(ctx context.Context, d time.Duration) error {
+	deadline := time.Time{}
+	if d >= 0 {
+		deadline = time.Now().Add(d)
+	}
+	if err := w.f.SetReadDeadline(deadline); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	// the events don't matter, any of them means the files must be looked at again.
+	_, err := w.f.Read(w.buf)
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case err == nil || errors.Is(err, os.ErrDeadlineExceeded):
+		return nil
+	}
+	return err
+}
+
+func (w *inotifyWaker) close() error {
+	close(w.stop)
+	return w.f.Close()
+}
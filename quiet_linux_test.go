@@ -0,0 +1,50 @@
+package times
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInotifyWaker(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := newWaker(ctx, dir, time.Hour)
+	defer func() { w.close() }()
+	if _, ok := w.(*inotifyWaker); !ok {
+		t.Fatalf("got waker %T, want *inotifyWaker", w)
+	}
+
+	// times out without events.
+	if err := w.wait(ctx, 10*time.Millisecond); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// wakes up when a write is done.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ioutil.WriteFile(filepath.Join(dir, "file"), []byte("data"), 0644)
+	}()
+	start := time.Now()
+	if err := w.wait(ctx, time.Minute); err != nil {
+		t.Fatal(err.Error())
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("woke up after %s", d)
+	}
+
+	// cancelling ctx interrupts the wait.
+	w.close()
+	w = newWaker(ctx, t.TempDir(), time.Hour)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := w.wait(ctx, -1); err != context.Canceled {
+		t.Errorf("got err %v, want %v", err, context.Canceled)
+	}
+}
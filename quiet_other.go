@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package times
+
+import (
+	"context"
+	"time"
+)
+
+// newWaker polls every interval, there is no portable way to watch dir.
+func newWaker(ctx context.Context, dir string, interval time.Duration) waker {
+	return pollWaker{interval: interval}
+}
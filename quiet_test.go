@@ -0,0 +1,189 @@
+package times
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitUntilQuietOld(t *testing.T) {
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		ts, err := WaitUntilQuiet(ctx, name, time.Minute)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(old, 0), t, Timespec.ModTime)
+	})
+}
+
+func TestWaitUntilQuiet(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		done := writeFor(f, 10, 10*time.Millisecond)
+
+		ts, err := WaitUntilQuiet(context.Background(), f.Name(), 50*time.Millisecond)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		select {
+		case <-done:
+		default:
+			t.Error("quiet while the file is still written")
+		}
+		if size := ts.(Times).size; size != 40 {
+			t.Errorf("quiet at size %d, want 40", size)
+		}
+	})
+}
+
+func TestWaitUntilQuietCtx(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := WaitUntilQuiet(ctx, f.Name(), time.Minute)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got err %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestWaitUntilQuietErr(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "missing")
+	if _, err := WaitUntilQuiet(context.Background(), name, time.Minute); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+}
+
+func TestWaitDirQuiet(t *testing.T) {
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		quiet := make(chan string)
+		errc := make(chan error, 1)
+		go func() {
+			errc <- WaitDirQuiet(ctx, dir, 50*time.Millisecond, func(path string, ts Timespec) error {
+				quiet <- path
+				return nil
+			})
+		}()
+
+		expectQuiet := func(want string) {
+			t.Helper()
+			select {
+			case got := <-quiet:
+				if got != want {
+					t.Errorf("got quiet file %s, want %s", got, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %s", want)
+			}
+		}
+		expectQuiet(name)
+
+		upload, err := os.Create(filepath.Join(dir, "upload"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		done := writeFor(upload, 10, 10*time.Millisecond)
+		expectQuiet(upload.Name())
+		select {
+		case <-done:
+		default:
+			t.Error("quiet while the file is still written")
+		}
+		upload.Close()
+
+		// a quiet file is only reported again once it changes.
+		if err := ioutil.WriteFile(name, []byte("again"), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		expectQuiet(name)
+
+		cancel()
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Errorf("got err %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestWaitDirQuietErr(t *testing.T) {
+	oldFileTest(t, func(dir, name string, old time.Time) {
+		errStop := errors.New("stop")
+		err := WaitDirQuiet(context.Background(), dir, time.Minute, func(path string, ts Timespec) error {
+			return errStop
+		})
+		if err != errStop {
+			t.Errorf("got err %v, want %v", err, errStop)
+		}
+	})
+
+	err := WaitDirQuiet(context.Background(), filepath.Join(t.TempDir(), "missing"), time.Minute, nil)
+	if !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+}
+
+func TestPollWaker(t *testing.T) {
+	w := pollWaker{interval: 10 * time.Millisecond}
+	start := time.Now()
+	if err := w.wait(context.Background(), -1); err != nil {
+		t.Fatal(err.Error())
+	}
+	if d := time.Since(start); d < 10*time.Millisecond || d > time.Second {
+		t.Errorf("waited %s, want the 10ms interval", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.wait(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("got err %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestQuietFile(t *testing.T) {
+	now := time.Now()
+	var q quietFile
+
+	q.update(Times{Mtime: now.Add(-time.Second)}, now)
+	if left := q.left(time.Minute, now); left != time.Minute-time.Second {
+		t.Errorf("got %s left, want %s", left, time.Minute-time.Second)
+	}
+
+	// a modification time in the future doesn't make the file quiet for longer.
+	q = quietFile{}
+	q.update(Times{Mtime: now.Add(time.Hour)}, now)
+	if left := q.left(time.Minute, now); left != time.Minute {
+		t.Errorf("got %s left, want %s", left, time.Minute)
+	}
+
+	// a modification time set back restarts the quiet time.
+	q.done = true
+	later := now.Add(time.Second)
+	q.update(Times{Mtime: now.Add(-time.Hour)}, later)
+	if left := q.left(time.Minute, later); left != time.Minute || q.done {
+		t.Errorf("got %s left and done %v, want %s and false", left, q.done, time.Minute)
+	}
+}
+
+// writeFor writes 4 bytes to f n times, every interval.
+func writeFor(f *os.File, n int, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			f.Write([]byte("data"))
+			time.Sleep(interval)
+		}
+	}()
+	return done
+}
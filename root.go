@@ -0,0 +1,55 @@
+//go:build go1.24
+// +build go1.24
+
+package times
+
+import "os"
+
+// StatRoot returns the Timespec for name, which is resolved beneath root:
+// like root.Stat, it fails for paths and symlinks which escape root.
+// On linux the file is opened with openat2(2) RESOLVE_BENEATH, or through root where
+// openat2 is not available, and its times are read with statx(2), so birth times are
+// available. Other platforms use root.Stat.
+func StatRoot(root *os.Root, name string) (Timespec, error) {
+	return DefaultStatter.StatRoot(root, name)
+}
+
+// LstatRoot returns the Timespec for name, which is resolved beneath root,
+// and does not follow a symlink in the last element of name, see StatRoot.
+func LstatRoot(root *os.Root, name string) (Timespec, error) {
+	return DefaultStatter.LstatRoot(root, name)
+}
+
+// StatRoot returns the Timespec for name resolved beneath root, see StatRoot.
+func (s *Statter) StatRoot(root *os.Root, name string) (Timespec, error) {
+	var t Times
+	if err := s.statRootInto(root, name, false, &t); err != nil {
+		return nil, err
+	}
+	if err := s.check(opStat, name, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LstatRoot returns the Timespec for name resolved beneath root, and does not
+// follow a symlink in the last element of name, see StatRoot.
+func (s *Statter) LstatRoot(root *os.Root, name string) (Timespec, error) {
+	var t Times
+	if err := s.statRootInto(root, name, true, &t); err != nil {
+		return nil, err
+	}
+	if err := s.check(opLstat, name, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// rootStatInto is the os.Root only implementation of StatRoot and LstatRoot.
+func rootStatInto(root *os.Root, name string, nofollow bool, dst *Times) error {
+	sf := root.Stat
+	if nofollow {
+		sf = root.Lstat
+	}
+	return statInto(name, sf, dst)
+}
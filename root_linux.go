@@ -0,0 +1,132 @@
+//go:build go1.24
+// +build go1.24
+
+package times
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// linux 5.5 and earlier does not support openat2, and seccomp filters
+	// (e.g. in containers) may deny it with EPERM.
+	supportsOpenat2 int32 = 1
+	openat2Func           = unix.Openat2
+)
+
+func (s *Statter) statRootInto(root *os.Root, name string, nofollow bool, dst *Times) error {
+	if !isStatXSupported() {
+		return s.rootFallback(root, name, nofollow, dst)
+	}
+	if atomic.LoadInt32(&supportsOpenat2) == 0 {
+		return s.statRootFileInto(root, name, nofollow, dst)
+	}
+
+	dir, err := root.Open(".")
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	sc, err := dir.SyscallConn()
+	if err != nil {
+		return pathErr("openat2", name, err)
+	}
+
+	how := unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	if nofollow {
+		how.Flags |= unix.O_NOFOLLOW
+	}
+
+	var fd int
+	var openErr error
+	if err := sc.Control(func(dirfd uintptr) {
+		fd, openErr = openat2Func(int(dirfd), name, &how)
+	}); err != nil {
+		return pathErr("openat2", name, err)
+	}
+	if openErr != nil {
+		if errors.Is(openErr, unix.ENOSYS) || errors.Is(openErr, unix.EPERM) {
+			atomic.StoreInt32(&supportsOpenat2, 0)
+			return s.statRootFileInto(root, name, nofollow, dst)
+		}
+		return pathErr("openat2", name, openErr)
+	}
+	defer unix.Close(fd)
+
+	err = s.statXInto(fd, "", unix.AT_EMPTY_PATH, dst)
+	if err == nil || !isStatXUnsupported(err) {
+		return pathErr(rootOp(nofollow), name, err)
+	}
+	return s.rootFallback(root, name, nofollow, dst)
+}
+
+// statRootFileInto is statRootInto without openat2: name is opened through root,
+// which resolves it beneath root, and its times are read with statx(2) from the file.
+// Without following a symlink, the directory of name is opened instead and the
+// last element of name is stat'd relative to it.
+func (s *Statter) statRootFileInto(root *os.Root, name string, nofollow bool, dst *Times) error {
+	path, base, flags := name, "", unix.AT_EMPTY_PATH
+	if nofollow {
+		dir := "."
+		if i := strings.LastIndexByte(name, '/'); i >= 0 {
+			dir, base = name[:i+1], name[i+1:]
+		} else {
+			base = name
+		}
+		// "." and ".." are not symlinks, and may be resolved out of dir.
+		if base != "" && base != "." && base != ".." {
+			path, flags = dir, unix.AT_SYMLINK_NOFOLLOW
+		} else {
+			base = ""
+		}
+	}
+
+	// O_NONBLOCK, so opening a FIFO does not wait for a writer.
+	f, err := root.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK, 0)
+	if os.IsPermission(err) {
+		// a stat does not need the file to be readable, root.Stat does not either.
+		return s.rootFallback(root, name, nofollow, dst)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc, err := f.SyscallConn()
+	if err != nil {
+		return pathErr(rootOp(nofollow), name, err)
+	}
+	var statxErr error
+	if err := sc.Control(func(fd uintptr) {
+		statxErr = s.statXInto(int(fd), base, flags, dst)
+	}); err != nil {
+		return pathErr(rootOp(nofollow), name, err)
+	}
+	if statxErr == nil || !isStatXUnsupported(statxErr) {
+		return pathErr(rootOp(nofollow), name, statxErr)
+	}
+	return s.rootFallback(root, name, nofollow, dst)
+}
+
+func (s *Statter) rootFallback(root *os.Root, name string, nofollow bool, dst *Times) error {
+	if s.strict {
+		return pathErr(rootOp(nofollow), name, ErrStatxUnavailable)
+	}
+	return rootStatInto(root, name, nofollow, dst)
+}
+
+func rootOp(nofollow bool) string {
+	if nofollow {
+		return opLstat
+	}
+	return opStat
+}
@@ -0,0 +1,136 @@
+//go:build go1.24
+// +build go1.24
+
+package times
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestStatRootStatx(t *testing.T) {
+	rootTest(t, func(root *os.Root) {
+		ts, err := LstatRoot(root, "link")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if src := SourceOf(ts); src != SourceStatx {
+			t.Errorf("SourceOf() = %s, want %s", src, SourceStatx)
+		}
+	})
+}
+
+func TestStatRootFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		disable func() func()
+		source  Source
+	}{
+		{name: "statx unsupported", disable: func() func() { return setStatx(unsupportedStatx) }, source: SourceStat},
+		{name: "openat2 unsupported", disable: func() func() {
+			atomic.StoreInt32(&supportsOpenat2, 0)
+			return func() { atomic.StoreInt32(&supportsOpenat2, 1) }
+		}, source: SourceStatx},
+		{name: "openat2 denied", disable: func() func() {
+			openat2Func = func(int, string, *unix.OpenHow) (int, error) { return -1, unix.EPERM }
+			return func() {
+				openat2Func = unix.Openat2
+				atomic.StoreInt32(&supportsOpenat2, 1)
+			}
+		}, source: SourceStatx},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restore := test.disable()
+			defer restore()
+
+			rootTest(t, func(root *os.Root) {
+				ts, err := StatRoot(root, "link")
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				timespecTest(ts, newInterval(time.Now().Add(offsetTime), time.Second), t, Timespec.AccessTime, Timespec.ModTime)
+				if src := SourceOf(ts); src != test.source {
+					t.Errorf("SourceOf() = %s, want %s", src, test.source)
+				}
+
+				if _, err := StatRoot(root, filepath.Join("..", "outside")); err == nil {
+					t.Error("StatRoot escaped the root")
+				}
+				if _, err := StatRoot(root, "escape"); err == nil {
+					t.Error("StatRoot escaped the root")
+				}
+				if _, err := LstatRoot(root, filepath.Join("subdir", "..", "..", "outside")); err == nil {
+					t.Error("LstatRoot escaped the root")
+				}
+
+				strict := NewStatter(WithStrict())
+				if test.source == SourceStat {
+					_, err = strict.LstatRoot(root, "link")
+					checkPathError(t, err, "lstatx", "link", ErrStatxUnavailable)
+					return
+				}
+
+				// the symlink itself, not the file.
+				link, err := strict.LstatRoot(root, "link")
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				if link.ModTime().Equal(ts.ModTime()) {
+					t.Error("LstatRoot followed the symlink")
+				}
+				if _, err := strict.LstatRoot(root, filepath.Join("subdir", "..")); err != nil {
+					t.Error(err.Error())
+				}
+				want, err := Stat(filepath.Join(root.Name(), "file"))
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				if ts.HasBirthTime() != want.HasBirthTime() {
+					t.Errorf("got HasBirthTime() %v, want %v", ts.HasBirthTime(), want.HasBirthTime())
+				}
+			})
+		})
+	}
+}
+
+func TestStatRootStrictBtime(t *testing.T) {
+	restore := setStatx(fakeSupportedStatx(statxT(time.Now(), false)))
+	defer restore()
+
+	rootTest(t, func(root *os.Root) {
+		_, err := NewStatter(WithStrict()).StatRoot(root, "file")
+		if !errors.Is(err, ErrBirthTimeUnavailable) {
+			t.Errorf("got err %v, want %v", err, ErrBirthTimeUnavailable)
+		}
+	})
+}
+
+func TestStatRootOpenat2Denied(t *testing.T) {
+	calls := 0
+	openat2Func = func(int, string, *unix.OpenHow) (int, error) {
+		calls++
+		return -1, unix.EPERM
+	}
+	defer func() {
+		openat2Func = unix.Openat2
+		atomic.StoreInt32(&supportsOpenat2, 1)
+	}()
+
+	rootTest(t, func(root *os.Root) {
+		for i := 0; i < 2; i++ {
+			if _, err := StatRoot(root, "file"); err != nil {
+				t.Fatal(err.Error())
+			}
+		}
+		if calls != 1 {
+			t.Errorf("got %d calls to openat2, want 1", calls)
+		}
+	})
+}
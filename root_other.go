@@ -0,0 +1,10 @@
+//go:build go1.24 && !linux
+// +build go1.24,!linux
+
+package times
+
+import "os"
+
+func (s *Statter) statRootInto(root *os.Root, name string, nofollow bool, dst *Times) error {
+	return rootStatInto(root, name, nofollow, dst)
+}
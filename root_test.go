@@ -0,0 +1,111 @@
+//go:build go1.24
+// +build go1.24
+
+package times
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// creates a root dir next to an outside file, with symlinks inside the root
+// which escape it, and cleans them up after the test is run.
+func rootTest(t *testing.T, testFunc func(root *os.Root)) {
+	parent := t.TempDir()
+
+	outside := filepath.Join(parent, "outside")
+	if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(parent, "root")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(offsetTime)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	links := map[string]string{
+		"link":            "file",
+		"escape":          filepath.Join("..", "outside"),
+		"absolute-escape": outside,
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	testFunc(root)
+}
+
+func TestStatRoot(t *testing.T) {
+	rootTest(t, func(root *os.Root) {
+		tests := []struct {
+			name string
+			stat func(*os.Root, string) (Timespec, error)
+			want time.Time
+		}{
+			{name: "file", stat: StatRoot, want: time.Now().Add(offsetTime)},
+			{name: filepath.Join("subdir", "..", "file"), stat: StatRoot, want: time.Now().Add(offsetTime)},
+			{name: "subdir", stat: StatRoot, want: time.Now()},
+			{name: ".", stat: StatRoot, want: time.Now()},
+			{name: "link", stat: StatRoot, want: time.Now().Add(offsetTime)},
+			{name: "link", stat: LstatRoot, want: time.Now()},
+			{name: "escape", stat: LstatRoot, want: time.Now()},
+		}
+		for _, test := range tests {
+			ts, err := test.stat(root, test.name)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			timespecTest(ts, newInterval(test.want, time.Second), t, Timespec.AccessTime, Timespec.ModTime)
+		}
+	})
+}
+
+func TestStatRootEscape(t *testing.T) {
+	rootTest(t, func(root *os.Root) {
+		escapes := []string{
+			filepath.Join("..", "outside"),
+			filepath.Join("subdir", "..", "..", "outside"),
+			"escape",
+			"absolute-escape",
+		}
+		for _, name := range escapes {
+			if _, err := StatRoot(root, name); err == nil {
+				t.Errorf("StatRoot(%q) escaped the root", name)
+			}
+		}
+		for _, name := range escapes[:2] {
+			if _, err := LstatRoot(root, name); err == nil {
+				t.Errorf("LstatRoot(%q) escaped the root", name)
+			}
+		}
+	})
+}
+
+func TestStatRootNotExist(t *testing.T) {
+	rootTest(t, func(root *os.Root) {
+		if _, err := StatRoot(root, "missing"); !os.IsNotExist(err) {
+			t.Errorf("got err %v, want not exist", err)
+		}
+	})
+}
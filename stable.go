@@ -0,0 +1,102 @@
+package times
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// StableOptions configures StableStat.
+type StableOptions struct {
+	// Rounds is the number of samples in a row which must agree,
+	// zero means 3 unless For is set.
+	Rounds int
+
+	// For is how long the samples must agree, zero means any time.
+	For time.Duration
+
+	// Interval is the time between samples, zero means 100ms.
+	Interval time.Duration
+
+	// NoFollow uses Lstat instead of Stat.
+	NoFollow bool
+}
+
+func (o StableOptions) rounds() int {
+	if o.Rounds == 0 && o.For == 0 {
+		return 3
+	}
+	return o.Rounds
+}
+
+func (o StableOptions) interval() time.Duration {
+	if o.Interval == 0 {
+		return 100 * time.Millisecond
+	}
+	return o.Interval
+}
+
+// StableStat samples the times of name until its modification time, change time
+// (where available) and size stop changing: for opts.Rounds samples in a row and
+// for at least opts.For. It is useful for files which may still be written by
+// another process. The settled Timespec is returned, or ctx.Err() if ctx is done
+// before the file settles, or the error of a failed sample (e.g. the file was removed).
+func StableStat(ctx context.Context, name string, opts StableOptions) (Timespec, error) {
+	rounds, interval := opts.rounds(), opts.interval()
+
+	var (
+		last, cur Times
+		since     time.Time
+		agreed    int
+	)
+	for {
+		if err := stableSample(name, opts.NoFollow, &cur); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if agreed > 0 && sameSample(last, cur) {
+			agreed++
+		} else {
+			agreed, since = 1, now
+		}
+		last = cur
+		if agreed >= rounds && now.Sub(since) >= opts.For {
+			return cur, nil
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// stableSample fills dst with the times and the size of name.
+func stableSample(name string, nofollow bool, dst *Times) error {
+	statInto, stat := StatInto, os.Stat
+	if nofollow {
+		statInto, stat = LstatInto, os.Lstat
+	}
+	if err := statInto(name, dst); err != nil {
+		return err
+	}
+	if !dst.hasSize && dst.Source != SourceStatx {
+		// not every platform gets the size with the times, statx(2) is asked for it.
+		fi, err := stat(name)
+		if err != nil {
+			return err
+		}
+		dst.size, dst.hasSize = fi.Size(), true
+	}
+	return nil
+}
+
+// sameSample reports whether a and b have the same modification time, change time and size.
+func sameSample(a, b Times) bool {
+	return a.Mtime.Equal(b.Mtime) &&
+		a.HasCtime == b.HasCtime && a.Ctime.Equal(b.Ctime) &&
+		a.size == b.size
+}
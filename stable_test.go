@@ -0,0 +1,107 @@
+package times
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStableStat(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		start := time.Now()
+		ts, err := StableStat(context.Background(), f.Name(), StableOptions{Interval: time.Millisecond, For: 20 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if d := time.Since(start); d < 20*time.Millisecond {
+			t.Errorf("settled after %s, want at least 20ms", d)
+		}
+		want, err := Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !want.(Times).Equal(ts.(Times)) {
+			t.Errorf("got %v, want %v", ts, want)
+		}
+	})
+}
+
+func TestStableStatWriter(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 10; i++ {
+				f.Write([]byte("data"))
+				time.Sleep(5 * time.Millisecond)
+			}
+		}()
+
+		ts, err := StableStat(context.Background(), f.Name(), StableOptions{Rounds: 5, Interval: 10 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		<-done
+		if size := ts.(Times).size; size != 40 {
+			t.Errorf("settled at size %d, want 40", size)
+		}
+	})
+}
+
+func TestStableStatNeverSettles(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					f.Write([]byte("data"))
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}()
+
+		_, err := StableStat(ctx, f.Name(), StableOptions{Rounds: 20, Interval: 5 * time.Millisecond})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got err %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestStableStatErr(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "missing")
+	if _, err := StableStat(context.Background(), name, StableOptions{}); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+}
+
+func TestSameSample(t *testing.T) {
+	now := time.Now()
+	a := Times{Mtime: now, Ctime: now, HasCtime: true, size: 1, hasSize: true}
+	tests := []struct {
+		name string
+		b    func(b *Times)
+		want bool
+	}{
+		{"same", func(b *Times) { b.Atime = now.Add(time.Second) }, true},
+		{"mtime", func(b *Times) { b.Mtime = now.Add(time.Nanosecond) }, false},
+		{"ctime", func(b *Times) { b.Ctime = now.Add(time.Nanosecond) }, false},
+		{"size", func(b *Times) { b.size = 2 }, false},
+	}
+	for _, test := range tests {
+		b := a
+		test.b(&b)
+		if got := sameSample(a, b); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
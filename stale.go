@@ -0,0 +1,180 @@
+package times
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// StaleOptions configures IsStale.
+type StaleOptions struct {
+	// MissingTargetsOK makes missing targets not stale, they are stale by default.
+	MissingTargetsOK bool
+
+	// Ctime uses the newer of the modification and the change time of the
+	// dependencies, so a dependency whose modification time was set back (by tar -x,
+	// touch -r, ...) still counts as changed. Targets always use their modification time.
+	Ctime bool
+
+	// Tolerance is how much newer than a target a dependency must be to make it stale,
+	// for filesystems whose times are not precise (2s for FAT, 1s for HFS+, ...) or whose
+	// clocks differ (NFS).
+	Tolerance time.Duration
+
+	// Trees treats directories as the trees beneath them: a directory target is as old
+	// as the oldest file in it, a directory dependency is as new as the newest path in it
+	// (including the directories, whose times change when a file is removed).
+	Trees bool
+
+	// Glob expands the targets and the dependencies with filepath.Glob. A target pattern
+	// which matches nothing is kept as it is, so it is reported missing (and stale unless
+	// MissingTargetsOK is set), as is a dependency pattern without meta characters.
+	Glob bool
+}
+
+// StaleReason is why a target is stale.
+type StaleReason struct {
+	Target string
+
+	// Dep is the newest dependency, which is newer than Target,
+	// it is empty if Target is missing.
+	Dep string
+
+	// TargetTime and DepTime are the times which were compared.
+	TargetTime, DepTime time.Time
+
+	// Missing is set if Target does not exist.
+	Missing bool
+}
+
+// StaleReport is the result of IsStale.
+type StaleReport struct {
+	// Stale is set if any target is stale.
+	Stale bool
+
+	// Reasons has the reason of each stale target, in the order of the targets.
+	Reasons []StaleReason
+}
+
+// IsStale reports whether any of the targets is stale, like make(1) would: if it is
+// missing, or if any of the deps is newer than it. Everything is stat'd with Stat, so
+// symlinks are followed. A missing dependency is an error.
+func IsStale(targets, deps []string, opts StaleOptions) (StaleReport, error) {
+	var r StaleReport
+	if opts.Glob {
+		var err error
+		if targets, err = globAll(targets, true); err != nil {
+			return r, err
+		}
+		if deps, err = globAll(deps, false); err != nil {
+			return r, err
+		}
+	}
+
+	var newest string
+	var newestTime time.Time
+	for _, dep := range deps {
+		path, t, err := staleTime(dep, opts, true)
+		if err != nil {
+			return r, err
+		}
+		if newest == "" || t.After(newestTime) {
+			newest, newestTime = path, t
+		}
+	}
+
+	for _, target := range targets {
+		path, t, err := staleTime(target, opts, false)
+		switch {
+		case os.IsNotExist(err):
+			if !opts.MissingTargetsOK {
+				r.Reasons = append(r.Reasons, StaleReason{Target: target, Missing: true})
+			}
+		case err != nil:
+			return r, err
+		case newest != "" && newestTime.Sub(t) > opts.Tolerance:
+			r.Reasons = append(r.Reasons, StaleReason{Target: path, Dep: newest, TargetTime: t, DepTime: newestTime})
+		}
+	}
+	r.Stale = len(r.Reasons) > 0
+	return r, nil
+}
+
+// staleTime returns the time of name to compare for IsStale, the newest one for a
+// dependency and the oldest one for a target, and the path it is the time of.
+func staleTime(name string, opts StaleOptions, dep bool) (string, time.Time, error) {
+	var t Times
+	if err := StatInto(name, &t); err != nil {
+		return name, time.Time{}, err
+	}
+	if !opts.Trees {
+		return name, staleTimeOf(t, opts, dep), nil
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return name, time.Time{}, err
+	}
+	if !fi.IsDir() {
+		return name, staleTimeOf(t, opts, dep), nil
+	}
+
+	// an empty target directory is as old as itself.
+	path, best, found := name, staleTimeOf(t, opts, dep), dep
+	err = filepath.WalkDir(name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == name || !dep && d.IsDir() {
+			return nil
+		}
+		var pt Times
+		if err := StatInto(p, &pt); err != nil {
+			if os.IsNotExist(err) {
+				// removed while walking, or a broken symlink.
+				return nil
+			}
+			return err
+		}
+		t := staleTimeOf(pt, opts, dep)
+		if !found || dep && t.After(best) || !dep && t.Before(best) {
+			path, best, found = p, t, true
+		}
+		return nil
+	})
+	return path, best, err
+}
+
+func staleTimeOf(t Times, opts StaleOptions, dep bool) time.Time {
+	if dep && opts.Ctime && t.HasCtime && t.Ctime.After(t.Mtime) {
+		return t.Ctime
+	}
+	return t.Mtime
+}
+
+// globAll expands patterns with filepath.Glob, see StaleOptions.Glob.
+// The patterns which match nothing are kept if targets is set.
+func globAll(patterns []string, targets bool) ([]string, error) {
+	var names []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 && (targets || !hasGlobMeta(pattern)) {
+			matches = []string{pattern}
+		}
+		names = append(names, matches...)
+	}
+	return names, nil
+}
+
+func hasGlobMeta(pattern string) bool {
+	magic := `*?[\`
+	if runtime.GOOS == "windows" {
+		magic = `*?[`
+	}
+	return strings.ContainsAny(pattern, magic)
+}
@@ -0,0 +1,212 @@
+package times
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// staleTest creates the files in mtimes with those modification times in a new dir,
+// the base of the times is an hour ago.
+func staleTest(t *testing.T, mtimes map[string]time.Duration) (string, func(name string) string) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	path := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+	for name, d := range mtimes {
+		if err := os.MkdirAll(filepath.Dir(path(name)), 0755); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := ioutil.WriteFile(path(name), nil, 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Chtimes(path(name), base.Add(d), base.Add(d)); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	return dir, path
+}
+
+func expectStale(t *testing.T, r StaleReport, want ...StaleReason) {
+	t.Helper()
+	if r.Stale != (len(want) > 0) || len(r.Reasons) != len(want) {
+		t.Fatalf("got %+v, want reasons %+v", r, want)
+	}
+	for i, got := range r.Reasons {
+		if got.Target != want[i].Target || got.Dep != want[i].Dep || got.Missing != want[i].Missing {
+			t.Errorf("got reason %+v, want %+v", got, want[i])
+		}
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	_, path := staleTest(t, map[string]time.Duration{
+		"old.o": 0,
+		"new.o": 2 * time.Second,
+		"a.c":   time.Second,
+		"b.c":   time.Second / 2,
+	})
+
+	r, err := IsStale([]string{path("old.o"), path("new.o")}, []string{path("b.c"), path("a.c")}, StaleOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r, StaleReason{Target: path("old.o"), Dep: path("a.c")})
+	if d := r.Reasons[0].DepTime.Sub(r.Reasons[0].TargetTime); d != time.Second {
+		t.Errorf("got the dependency %s newer, want 1s", d)
+	}
+
+	// within the tolerance.
+	r, err = IsStale([]string{path("old.o")}, []string{path("a.c")}, StaleOptions{Tolerance: time.Second})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r)
+
+	// no dependencies.
+	r, err = IsStale([]string{path("old.o")}, nil, StaleOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r)
+}
+
+func TestIsStaleMissing(t *testing.T) {
+	_, path := staleTest(t, map[string]time.Duration{"a.c": 0})
+
+	r, err := IsStale([]string{path("a.o")}, []string{path("a.c")}, StaleOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r, StaleReason{Target: path("a.o"), Missing: true})
+
+	r, err = IsStale([]string{path("a.o")}, []string{path("a.c")}, StaleOptions{MissingTargetsOK: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r)
+
+	_, err = IsStale([]string{path("a.c")}, []string{path("missing.c")}, StaleOptions{})
+	var perr *os.PathError
+	if !errors.As(err, &perr) || !os.IsNotExist(err) || perr.Path != path("missing.c") {
+		t.Errorf("got err %v, want a not exist *os.PathError for missing.c", err)
+	}
+}
+
+func TestIsStaleCtime(t *testing.T) {
+	_, path := staleTest(t, map[string]time.Duration{"a.o": time.Second, "a.c": 0})
+	ts, err := Stat(path("a.c"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !ts.HasChangeTime() {
+		t.Skip("change times not available")
+	}
+
+	// the change time of a.c is now, it was just set up.
+	r, err := IsStale([]string{path("a.o")}, []string{path("a.c")}, StaleOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r)
+	r, err = IsStale([]string{path("a.o")}, []string{path("a.c")}, StaleOptions{Ctime: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r, StaleReason{Target: path("a.o"), Dep: path("a.c")})
+}
+
+func TestIsStaleTrees(t *testing.T) {
+	dir, path := staleTest(t, map[string]time.Duration{
+		"out/a.o":     2 * time.Second,
+		"out/sub/b.o": 0,
+		"src/a.c":     time.Second,
+		"src/sub/b.c": time.Second,
+	})
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{"out", "out/sub", "src", "src/sub"} {
+		if err := os.Chtimes(path(name), old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	r, err := IsStale([]string{path("out")}, []string{path("src")}, StaleOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r)
+
+	r, err = IsStale([]string{path("out")}, []string{path("src")}, StaleOptions{Trees: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(r.Reasons) != 1 || r.Reasons[0].Target != path("out/sub/b.o") || filepath.Dir(r.Reasons[0].Dep) == dir {
+		t.Errorf("got %+v, want out/sub/b.o made stale by a source", r)
+	}
+
+	// a removed source changes the time of its directory.
+	if err := os.Remove(path("src/sub/b.c")); err != nil {
+		t.Fatal(err.Error())
+	}
+	r, err = IsStale([]string{path("out/a.o")}, []string{path("src")}, StaleOptions{Trees: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r, StaleReason{Target: path("out/a.o"), Dep: path("src/sub")})
+
+	// an empty target directory.
+	if err := os.Mkdir(path("empty"), 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.Chtimes(path("empty"), old, old); err != nil {
+		t.Fatal(err.Error())
+	}
+	r, err = IsStale([]string{path("empty")}, []string{path("src/a.c")}, StaleOptions{Trees: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r, StaleReason{Target: path("empty"), Dep: path("src/a.c")})
+}
+
+func TestIsStaleGlob(t *testing.T) {
+	_, path := staleTest(t, map[string]time.Duration{
+		"a.o": time.Second,
+		"b.o": 0,
+		"a.c": time.Second / 2,
+		"b.h": 0,
+	})
+
+	r, err := IsStale([]string{path("*.o"), path("c.o")}, []string{path("*.c"), path("*.h"), path("*.y")}, StaleOptions{Glob: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r,
+		StaleReason{Target: path("b.o"), Dep: path("a.c")},
+		StaleReason{Target: path("c.o"), Missing: true},
+	)
+
+	// no outputs at all.
+	r, err = IsStale([]string{path("*.obj")}, []string{path("*.c")}, StaleOptions{Glob: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r, StaleReason{Target: path("*.obj"), Missing: true})
+	r, err = IsStale([]string{path("*.obj")}, []string{path("*.c")}, StaleOptions{Glob: true, MissingTargetsOK: true})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r)
+
+	// without Glob the patterns are paths.
+	r, err = IsStale([]string{path("*.o")}, nil, StaleOptions{})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectStale(t, r, StaleReason{Target: path("*.o"), Missing: true})
+
+	if _, err := IsStale([]string{path("[")}, nil, StaleOptions{Glob: true}); err != filepath.ErrBadPattern {
+		t.Errorf("got err %v, want %v", err, filepath.ErrBadPattern)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package times
+
+import (
+	"os"
+	"syscall"
+)
+
+func hasPlatformSys(fi os.FileInfo) bool {
+	_, ok := fi.Sys().(*syscall.Stat_t)
+	return ok
+}
+
+func fileIDOf(fi os.FileInfo) fileID {
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+}
@@ -0,0 +1,88 @@
+package times
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// AtFlag changes the behavior of StatAt.
+type AtFlag int
+
+const (
+	// AtSymlinkNoFollow makes StatAt return the times of a symlink itself, like Lstat.
+	AtSymlinkNoFollow AtFlag = 1 << iota
+)
+
+// StatAt returns the Timespec for name relative to the directory dir.
+// On linux the lookup is done relative to the open directory (statx(2) with dir's fd),
+// so it is cheaper than Stat on a full path and is not affected by renames of dir's parents.
+// Other platforms fall back to Stat or Lstat of filepath.Join(dir.Name(), name).
+func StatAt(dir *os.File, name string, flags AtFlag) (Timespec, error) {
+	return DefaultStatter.StatAt(dir, name, flags)
+}
+
+// StatAt returns the Timespec for name relative to the directory dir, see StatAt.
+func (s *Statter) StatAt(dir *os.File, name string, flags AtFlag) (Timespec, error) {
+	var t Times
+	if err := s.StatAtInto(dir, name, flags, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// StatAtInto fills dst with the times for name relative to the directory dir, see StatAt.
+func (s *Statter) StatAtInto(dir *os.File, name string, flags AtFlag, dst *Times) error {
+	if err := s.statAtInto(dir, name, flags, dst); err != nil {
+		return err
+	}
+	op := opStat
+	if flags&AtSymlinkNoFollow != 0 {
+		op = opLstat
+	}
+	return s.check(op, joinAt(dir, name), dst)
+}
+
+// joinAt returns the path StatAt looks up.
+func joinAt(dir *os.File, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir.Name(), name)
+}
+
+// DirEntry is an fs.DirEntry together with the Times of the entry.
+type DirEntry struct {
+	fs.DirEntry
+	Times
+}
+
+// ReadDirTimes reads all the entries of the directory dir, like (*os.File).ReadDir(-1),
+// and gets their times with StatAt. Like the entries themselves, the times of symlinks
+// are those of the link, not its target. Entries which are removed before their times
+// can be read are skipped.
+func ReadDirTimes(dir *os.File) ([]DirEntry, error) {
+	return DefaultStatter.ReadDirTimes(dir)
+}
+
+// ReadDirTimes reads all the entries of the directory dir with their times, see ReadDirTimes.
+func (s *Statter) ReadDirTimes(dir *os.File) ([]DirEntry, error) {
+	entries, err := dir.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	des := make([]DirEntry, 0, len(entries))
+	for _, e := range entries {
+		de := DirEntry{DirEntry: e}
+		if err := s.StatAtInto(dir, e.Name(), AtSymlinkNoFollow, &de.Times); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return des, err
+		}
+		des = append(des, de)
+	}
+	return des, nil
+}
@@ -0,0 +1,59 @@
+package times
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func (s *Statter) statAtInto(dir *os.File, name string, flags AtFlag, dst *Times) error {
+	op, atFlags := opStat, 0
+	if flags&AtSymlinkNoFollow != 0 {
+		op, atFlags = opLstat, unix.AT_SYMLINK_NOFOLLOW
+	}
+
+	sc, err := dir.SyscallConn()
+	if err != nil {
+		return pathErr(op, joinAt(dir, name), err)
+	}
+
+	var statErr error
+	err = sc.Control(func(fd uintptr) {
+		if isStatXSupported() {
+			statErr = s.statXInto(int(fd), name, atFlags, dst)
+			if statErr == nil || !isStatXUnsupported(statErr) {
+				return
+			}
+			// Fallback.
+		}
+		if s.strict {
+			statErr = ErrStatxUnavailable
+			return
+		}
+		op = "fstatat"
+		statErr = fstatatInto(int(fd), name, atFlags, dst)
+	})
+	if err == nil {
+		err = statErr
+	}
+	return pathErr(op, joinAt(dir, name), err)
+}
+
+func fstatatInto(dirfd int, name string, flags int, dst *Times) error {
+	var st unix.Stat_t
+	if err := unix.Fstatat(dirfd, name, &st, flags); err != nil {
+		return err
+	}
+	*dst = Times{
+		Atime:    time.Unix(st.Atim.Unix()),
+		Mtime:    time.Unix(st.Mtim.Unix()),
+		Ctime:    time.Unix(st.Ctim.Unix()),
+		HasCtime: true,
+		Source:   SourceStat,
+		id:       fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)},
+		size:     st.Size,
+		hasSize:  true,
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package times
+
+import "os"
+
+func (s *Statter) statAtInto(dir *os.File, name string, flags AtFlag, dst *Times) error {
+	if flags&AtSymlinkNoFollow != 0 {
+		return s.lstatInto(joinAt(dir, name), dst)
+	}
+	return s.statInto(joinAt(dir, name), dst)
+}
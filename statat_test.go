@@ -0,0 +1,117 @@
+package times
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// creates a dir with a file, a subdir and a symlink to the file whose
+// target is older than the link, and cleans it up after the test is run.
+func treeTest(t testing.TB, testFunc func(dir *os.File)) {
+	dirname, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dirname)
+
+	file := filepath.Join(dirname, "file")
+	if err := ioutil.WriteFile(file, []byte("file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(offsetTime)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dirname, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(file, filepath.Join(dirname, "symlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := os.Open(dirname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+	testFunc(dir)
+}
+
+func TestStatAt(t *testing.T) {
+	treeTest(t, func(dir *os.File) {
+		tests := []struct {
+			name  string
+			flags AtFlag
+			want  time.Time
+		}{
+			{name: "file", want: time.Now().Add(offsetTime)},
+			{name: "subdir", want: time.Now()},
+			{name: "symlink", want: time.Now().Add(offsetTime)},
+			{name: "symlink", flags: AtSymlinkNoFollow, want: time.Now()},
+			{name: filepath.Join(dir.Name(), "file"), want: time.Now().Add(offsetTime)},
+		}
+		for _, test := range tests {
+			ts, err := StatAt(dir, test.name, test.flags)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			timespecTest(ts, newInterval(test.want, time.Second), t, Timespec.AccessTime, Timespec.ModTime)
+		}
+	})
+}
+
+func TestStatAtErr(t *testing.T) {
+	treeTest(t, func(dir *os.File) {
+		_, err := StatAt(dir, "missing", 0)
+		checkPathError(t, err, "", filepath.Join(dir.Name(), "missing"), fs.ErrNotExist)
+	})
+}
+
+func TestReadDirTimes(t *testing.T) {
+	treeTest(t, func(dir *os.File) {
+		entries, err := ReadDirTimes(dir)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+		want := []struct {
+			name string
+			typ  fs.FileMode
+			time time.Time
+		}{
+			{name: "file", time: time.Now().Add(offsetTime)},
+			{name: "subdir", typ: fs.ModeDir, time: time.Now()},
+			{name: "symlink", typ: fs.ModeSymlink, time: time.Now()},
+		}
+		if len(entries) != len(want) {
+			t.Fatalf("got %d entries, want %d", len(entries), len(want))
+		}
+		for i, e := range entries {
+			if e.Name() != want[i].name || e.Type() != want[i].typ {
+				t.Errorf("entry %d = %s (%s), want %s (%s)", i, e.Name(), e.Type(), want[i].name, want[i].typ)
+			}
+			timespecTest(e.Times, newInterval(want[i].time, time.Second), t, Timespec.AccessTime, Timespec.ModTime)
+		}
+	})
+}
+
+func TestReadDirTimesErr(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		if _, err := ReadDirTimes(f); err == nil {
+			t.Error("expected an error reading a file as a directory")
+		}
+	})
+
+	treeTest(t, func(dir *os.File) {
+		dir.Close()
+		if _, err := ReadDirTimes(dir); err == nil {
+			t.Error("expected an error reading a closed directory")
+		}
+	})
+}
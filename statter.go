@@ -0,0 +1,217 @@
+package times
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrStatxUnavailable is returned by strict Statters on linux when the kernel
+// does not implement statx(2), instead of falling back to stat(2).
+var ErrStatxUnavailable = errors.New("statx not available")
+
+// Field is a set of file times.
+type Field uint8
+
+// The Field values, they can be combined with |.
+const (
+	FieldAtime Field = 1 << iota
+	FieldMtime
+	FieldCtime
+	FieldBtime
+
+	AllFields = FieldAtime | FieldMtime | FieldCtime | FieldBtime
+)
+
+var fieldNames = []struct {
+	f    Field
+	name string
+}{
+	{FieldAtime, "atime"},
+	{FieldMtime, "mtime"},
+	{FieldCtime, "ctime"},
+	{FieldBtime, "btime"},
+}
+
+func (f Field) String() string {
+	var names []string
+	for _, fn := range fieldNames {
+		if f&fn.f != 0 {
+			names = append(names, fn.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// UnavailableError is returned by strict Statters when some of the times
+// they were asked for are not available.
+type UnavailableError struct {
+	Missing Field
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Missing.String() + " not available"
+}
+
+// Is makes errors.Is(err, ErrChangeTimeUnavailable) and
+// errors.Is(err, ErrBirthTimeUnavailable) true when those times are missing.
+func (e *UnavailableError) Is(target error) bool {
+	switch target {
+	case ErrChangeTimeUnavailable:
+		return e.Missing&FieldCtime != 0
+	case ErrBirthTimeUnavailable:
+		return e.Missing&FieldBtime != 0
+	}
+	return false
+}
+
+// SyncMode controls how a Statter synchronizes times with a remote
+// filesystem (NFS, CIFS, ...) before returning them.
+type SyncMode int
+
+const (
+	// SyncAsStat does whatever stat(2) does, this is the default.
+	SyncAsStat SyncMode = iota
+
+	// ForceSync forces the times to be synchronized with the server.
+	ForceSync
+
+	// DontSync returns whatever is cached locally, even if it may be stale.
+	DontSync
+)
+
+// Option configures a Statter.
+type Option func(*Statter)
+
+// WithFields limits the times a Statter asks for to fields.
+// Times which were not asked for may still be returned if they are free to get,
+// but ChangeTime and BirthTime are only reported when they are present.
+func WithFields(fields Field) Option {
+	return func(s *Statter) { s.fields = fields }
+}
+
+// WithSync sets the SyncMode used by a Statter.
+func WithSync(mode SyncMode) Option {
+	return func(s *Statter) { s.sync = mode }
+}
+
+// WithNoAutomount stops a Statter from triggering the automount of
+// the last component of a path (e.g. autofs mount points).
+func WithNoAutomount() Option {
+	return func(s *Statter) { s.noAutomount = true }
+}
+
+// WithStrict makes a Statter return an error instead of silently returning fewer times:
+// it never falls back from statx(2) to stat(2) on linux (ErrStatxUnavailable), nor from
+// GetFileInformationByHandleEx to os.Stat on windows, and it returns an *UnavailableError
+// if any of the times asked for with WithFields (all of them by default) are missing.
+func WithStrict() Option {
+	return func(s *Statter) { s.strict = true }
+}
+
+// Statter gets file times with a fixed set of options.
+// WithFields, WithSync and WithNoAutomount only change the behavior of platforms which
+// support them (linux statx), they are ignored elsewhere. The zero Statter is the same
+// as DefaultStatter.
+type Statter struct {
+	fields      Field
+	sync        SyncMode
+	noAutomount bool
+	strict      bool
+}
+
+// DefaultStatter is the Statter used by Stat, Lstat, StatFile and their Into variants.
+var DefaultStatter = NewStatter()
+
+// NewStatter returns a Statter configured by opts.
+func NewStatter(opts ...Option) *Statter {
+	s := &Statter{fields: AllFields}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// With returns a copy of s with opts applied, the options of s are left unchanged.
+// It is an easy way to change options for a single call:
+//
+//	ts, err := times.DefaultStatter.With(times.WithStrict()).Stat(name)
+func (s *Statter) With(opts ...Option) *Statter {
+	c := *s
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+func (s *Statter) wants() Field {
+	if s.fields == 0 {
+		return AllFields
+	}
+	return s.fields
+}
+
+// check enforces strict mode on the times in t.
+func (s *Statter) check(op, name string, t *Times) error {
+	if !s.strict {
+		return nil
+	}
+	if missing := s.wants() &^ t.fields(); missing != 0 {
+		return pathErr(op, name, &UnavailableError{Missing: missing})
+	}
+	return nil
+}
+
+// Stat returns the Timespec for the given filename.
+func (s *Statter) Stat(name string) (Timespec, error) {
+	var t Times
+	if err := s.StatInto(name, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// StatInto fills dst with the times for the given filename.
+func (s *Statter) StatInto(name string, dst *Times) error {
+	if err := s.statInto(name, dst); err != nil {
+		return err
+	}
+	return s.check(opStat, name, dst)
+}
+
+// Lstat returns the Timespec for the given filename, and does not follow Symlinks.
+func (s *Statter) Lstat(name string) (Timespec, error) {
+	var t Times
+	if err := s.LstatInto(name, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LstatInto fills dst with the times for the given filename, and does not follow Symlinks.
+func (s *Statter) LstatInto(name string, dst *Times) error {
+	if err := s.lstatInto(name, dst); err != nil {
+		return err
+	}
+	return s.check(opLstat, name, dst)
+}
+
+// StatFile returns the Timespec for the given *os.File.
+func (s *Statter) StatFile(file *os.File) (Timespec, error) {
+	var t Times
+	if err := s.StatFileInto(file, &t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// StatFileInto fills dst with the times for the given *os.File.
+func (s *Statter) StatFileInto(file *os.File, dst *Times) error {
+	if err := s.statFileInto(file, dst); err != nil {
+		return err
+	}
+	return s.check(opFstat, file.Name(), dst)
+}
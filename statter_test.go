@@ -0,0 +1,121 @@
+package times
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStatter(t *testing.T) {
+	statters := map[string]*Statter{
+		"zero":         {},
+		"default":      DefaultStatter,
+		"force sync":   NewStatter(WithSync(ForceSync)),
+		"dont sync":    NewStatter(WithSync(DontSync)),
+		"no automount": NewStatter(WithNoAutomount()),
+	}
+	for name, s := range statters {
+		t.Run(name, func(t *testing.T) {
+			fileAndDirTest(t, func(name string) {
+				ts, err := s.Stat(name)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				timespecTest(ts, newInterval(time.Now(), time.Second), t)
+
+				ts, err = s.Lstat(name)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				timespecTest(ts, newInterval(time.Now(), time.Second), t)
+			})
+
+			fileTest(t, func(f *os.File) {
+				ts, err := s.StatFile(f)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				timespecTest(ts, newInterval(time.Now(), time.Second), t)
+			})
+		})
+	}
+}
+
+func TestStatterMtimeOnly(t *testing.T) {
+	s := NewStatter(WithFields(FieldMtime))
+	fileAndDirTest(t, func(name string) {
+		var ts Times
+		if err := s.StatInto(name, &ts); err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.ModTime)
+	})
+}
+
+func TestStatterErr(t *testing.T) {
+	s := NewStatter(WithSync(DontSync))
+	if _, err := s.Stat("badfile?"); err == nil {
+		t.Error("expected an error")
+	}
+	if _, err := s.Lstat("badfile?"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestStatterWith(t *testing.T) {
+	s := NewStatter(WithFields(FieldMtime))
+	strict := s.With(WithStrict())
+	if s.strict {
+		t.Error("With() changed the original Statter")
+	}
+	if !strict.strict || strict.fields != FieldMtime {
+		t.Errorf("With() = %+v, want strict mtime only Statter", strict)
+	}
+
+	fileAndDirTest(t, func(name string) {
+		ts, err := strict.Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t, Timespec.ModTime)
+	})
+}
+
+func TestStrictPlatformFields(t *testing.T) {
+	s := NewStatter(WithStrict(), WithFields(FieldAtime|FieldMtime|FieldCtime))
+	fileAndDirTest(t, func(name string) {
+		// platforms without ctime may only fail because it is missing.
+		_, err := s.Stat(name)
+		if err != nil && (HasChangeTime || !errors.Is(err, ErrChangeTimeUnavailable)) {
+			t.Fatal(err.Error())
+		}
+	})
+}
+
+func TestFieldString(t *testing.T) {
+	tests := map[Field]string{
+		0:                       "none",
+		FieldAtime:              "atime",
+		FieldMtime | FieldBtime: "mtime|btime",
+		AllFields:               "atime|mtime|ctime|btime",
+	}
+	for f, want := range tests {
+		if got := f.String(); got != want {
+			t.Errorf("Field(%d).String() = %q, want %q", f, got, want)
+		}
+	}
+}
+
+func TestUnavailableError(t *testing.T) {
+	err := error(&UnavailableError{Missing: FieldBtime})
+	if got, want := err.Error(), "btime not available"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(err, ErrBirthTimeUnavailable) {
+		t.Error("expected errors.Is(err, ErrBirthTimeUnavailable)")
+	}
+	if errors.Is(err, ErrChangeTimeUnavailable) {
+		t.Error("expected !errors.Is(err, ErrChangeTimeUnavailable)")
+	}
+}
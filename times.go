@@ -2,28 +2,81 @@
 package times
 
 import (
+	"errors"
 	"os"
 	"time"
 )
 
-// Get returns the Timespec for the given FileInfo
+// ErrChangeTimeUnavailable and ErrBirthTimeUnavailable report that a
+// Timespec does not carry the requested time, see UnavailableError.
+var (
+	ErrChangeTimeUnavailable = errors.New("ctime not available")
+	ErrBirthTimeUnavailable  = errors.New("birthtime not available")
+)
+
+// Get returns the Timespec for the given FileInfo.
+// If fi or fi.Sys() is a TimespecProvider its Timespec is returned. Otherwise,
+// if fi.Sys() was not created by the os package (e.g. fi comes from an archive
+// or a virtual fs.FS), only ModTime is known: the result has no access, change
+// or birth time and its Source is SourceModTime.
 func Get(fi os.FileInfo) Timespec {
-	return getTimespec(fi)
+	// Get is kept small enough to be inlined, so the Times need not escape
+	// if the caller does not keep them.
+	t, provided := getTimes(fi)
+	if provided != nil {
+		return provided
+	}
+	return t
 }
 
-type statFunc func(string) (os.FileInfo, error)
-
-func stat(name string, sf statFunc) (Timespec, error) {
-	fi, err := sf(name)
-	if err != nil {
-		return nil, err
+// getTimes returns the Times for Get, or the Timespec provided by fi.
+func getTimes(fi os.FileInfo) (t Times, provided Timespec) {
+	if ts, ok := providedTimespec(fi); ok {
+		return t, ts
 	}
-	return getTimespec(fi), nil
+	if !hasPlatformSys(fi) {
+		return Times{Mtime: fi.ModTime(), Source: SourceModTime}, nil
+	}
+	t.setFileInfo(fi, SourceFileInfo)
+	return t, nil
+}
+
+// Stat returns the Timespec for the given filename.
+func Stat(name string) (Timespec, error) {
+	return DefaultStatter.Stat(name)
+}
+
+// StatInto fills dst with the times for the given filename.
+func StatInto(name string, dst *Times) error {
+	return DefaultStatter.StatInto(name, dst)
+}
+
+// Lstat returns the Timespec for the given filename, and does not follow Symlinks.
+func Lstat(name string) (Timespec, error) {
+	return DefaultStatter.Lstat(name)
+}
+
+// LstatInto fills dst with the times for the given filename, and does not follow Symlinks.
+func LstatInto(name string, dst *Times) error {
+	return DefaultStatter.LstatInto(name, dst)
+}
+
+// StatFile returns the Timespec for the given *os.File.
+func StatFile(file *os.File) (Timespec, error) {
+	return DefaultStatter.StatFile(file)
 }
 
+// StatFileInto fills dst with the times for the given *os.File.
+func StatFileInto(file *os.File, dst *Times) error {
+	return DefaultStatter.StatFileInto(file, dst)
+}
+
+type statFunc func(string) (os.FileInfo, error)
+
 // Timespec provides access to file times.
 // ChangeTime() panics unless HasChangeTime() is true and
 // BirthTime() panics unless HasBirthTime() is true.
+// The ChangeTimeOK and BirthTimeOK functions never panic.
 type Timespec interface {
 	ModTime() time.Time
 	AccessTime() time.Time
@@ -33,6 +86,24 @@ type Timespec interface {
 	HasBirthTime() bool
 }
 
+// ChangeTimeOK returns the change time of ts and true,
+// or the zero time and false if ts does not have it.
+func ChangeTimeOK(ts Timespec) (time.Time, bool) {
+	if !ts.HasChangeTime() {
+		return time.Time{}, false
+	}
+	return ts.ChangeTime(), true
+}
+
+// BirthTimeOK returns the birth time of ts and true,
+// or the zero time and false if ts does not have it.
+func BirthTimeOK(ts Timespec) (time.Time, bool) {
+	if !ts.HasBirthTime() {
+		return time.Time{}, false
+	}
+	return ts.BirthTime(), true
+}
+
 type atime struct {
 	v time.Time
 }
@@ -72,3 +143,150 @@ type nobtime struct{}
 func (nobtime) HasBirthTime() bool { return false }
 
 func (nobtime) BirthTime() time.Time { panic("birthtime not available") }
+
+// Times is a concrete Timespec value. Unlike the Timespec returned by Stat,
+// a Times can be filled in place by StatInto, LstatInto and StatFileInto,
+// which lets callers reuse one value across many calls. On linux (with statx)
+// and for StatFileInto on windows that does not allocate; wherever the times are
+// read with os.Stat or os.Lstat, e.g. on the BSDs and darwin, the FileInfo they
+// return is still allocated, and StatInto on windows converts name to UTF-16.
+//
+// Ctime is only valid if HasCtime is true and
+// Btime is only valid if HasBtime is true.
+//
+// Source and StatxMask record how the times were obtained, they are useful
+// to explain why a time is missing, e.g. a Source of SourceStat means statx(2)
+// was unavailable, while a Source of SourceStatx with no STATX_BTIME bit
+// in StatxMask means the filesystem does not record birth times.
+type Times struct {
+	Atime    time.Time
+	Mtime    time.Time
+	Ctime    time.Time
+	Btime    time.Time
+	HasCtime bool
+	HasBtime bool
+
+	Source    Source
+	StatxMask uint32
+
+	id      fileID
+	size    int64
+	hasSize bool
+}
+
+// fileID identifies a file by its device and inode numbers,
+// it is zero when they are not known.
+type fileID struct {
+	dev, ino uint64
+}
+
+// idOf returns the fileID of ts, if it is a Times.
+func idOf(ts Timespec) fileID {
+	if t, ok := ts.(Times); ok {
+		return t.id
+	}
+	if t, ok := ts.(*Times); ok && t != nil {
+		return t.id
+	}
+	return fileID{}
+}
+
+// AccessTime returns t.Atime.
+func (t Times) AccessTime() time.Time { return t.Atime }
+
+// ModTime returns t.Mtime.
+func (t Times) ModTime() time.Time { return t.Mtime }
+
+// ChangeTime returns t.Ctime, it panics unless t.HasCtime is true.
+func (t Times) ChangeTime() time.Time {
+	if !t.HasCtime {
+		panic("ctime not available")
+	}
+	return t.Ctime
+}
+
+// BirthTime returns t.Btime, it panics unless t.HasBtime is true.
+func (t Times) BirthTime() time.Time {
+	if !t.HasBtime {
+		panic("birthtime not available")
+	}
+	return t.Btime
+}
+
+// HasChangeTime returns t.HasCtime.
+func (t Times) HasChangeTime() bool { return t.HasCtime }
+
+// HasBirthTime returns t.HasBtime.
+func (t Times) HasBirthTime() bool { return t.HasBtime }
+
+// Equal reports whether t and u hold the same times. Unlike ==, it compares
+// times with time.Time.Equal, ignores Ctime/Btime when they are not present
+// and ignores Source and StatxMask.
+func (t Times) Equal(u Times) bool {
+	if !t.Atime.Equal(u.Atime) || !t.Mtime.Equal(u.Mtime) {
+		return false
+	}
+	if t.HasCtime != u.HasCtime || (t.HasCtime && !t.Ctime.Equal(u.Ctime)) {
+		return false
+	}
+	return t.HasBtime == u.HasBtime && (!t.HasBtime || t.Btime.Equal(u.Btime))
+}
+
+// The STATX_ATIME and STATX_MTIME bits of Times.StatxMask.
+const (
+	statxAtime = 0x20
+	statxMtime = 0x40
+)
+
+// fields returns the times present in t.
+func (t Times) fields() Field {
+	f := FieldAtime | FieldMtime
+	switch t.Source {
+	case SourceModTime:
+		f = FieldMtime
+	case SourceStatx:
+		f = 0
+		if t.StatxMask&statxAtime != 0 {
+			f |= FieldAtime
+		}
+		if t.StatxMask&statxMtime != 0 {
+			f |= FieldMtime
+		}
+	}
+	if t.HasCtime {
+		f |= FieldCtime
+	}
+	if t.HasBtime {
+		f |= FieldBtime
+	}
+	return f
+}
+
+// setFileInfo replaces t with the times, the fileID and the size from fi, whose
+// Sys() must be the platform's. The times are read by setSys, without a Timespec
+// which would have to be allocated.
+func (t *Times) setFileInfo(fi os.FileInfo, src Source) {
+	*t = Times{Source: src, id: fileIDOf(fi), size: fi.Size(), hasSize: true}
+	t.setSys(fi)
+}
+
+// pathErr wraps a non-nil err in an *os.PathError, unless it already is one.
+func pathErr(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var perr *os.PathError
+	if errors.As(err, &perr) {
+		return err
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+func statInto(name string, sf statFunc, dst *Times) error {
+	fi, err := sf(name)
+	if err != nil {
+		return err
+	}
+	dst.setFileInfo(fi, SourceStat)
+	return nil
+}
@@ -19,21 +19,13 @@ const (
 	HasBirthTime  = false
 )
 
-type timespec struct {
-	atime
-	mtime
-	ctime
-	nobtime
-}
-
 func timespecToTime(ts syscall.StTimespec_t) time.Time {
 	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
 }
 
-func getTimespec(fi os.FileInfo) (t timespec) {
+func (t *Times) setSys(fi os.FileInfo) {
 	stat := fi.Sys().(*syscall.Stat_t)
-	t.atime.v = timespecToTime(stat.Atim)
-	t.mtime.v = timespecToTime(stat.Mtim)
-	t.ctime.v = timespecToTime(stat.Ctim)
-	return t
+	t.Atime = timespecToTime(stat.Atim)
+	t.Mtime = timespecToTime(stat.Mtim)
+	t.Ctime, t.HasCtime = timespecToTime(stat.Ctim), true
 }
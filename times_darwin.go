@@ -19,22 +19,14 @@ const (
 	HasBirthTime  = true
 )
 
-type timespec struct {
-	atime
-	mtime
-	ctime
-	btime
-}
-
 func timespecToTime(ts syscall.Timespec) time.Time {
 	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
 }
 
-func getTimespec(fi os.FileInfo) (t timespec) {
+func (t *Times) setSys(fi os.FileInfo) {
 	stat := fi.Sys().(*syscall.Stat_t)
-	t.atime.v = timespecToTime(stat.Atimespec)
-	t.mtime.v = timespecToTime(stat.Mtimespec)
-	t.ctime.v = timespecToTime(stat.Ctimespec)
-	t.btime.v = timespecToTime(stat.Birthtimespec)
-	return t
+	t.Atime = timespecToTime(stat.Atimespec)
+	t.Mtime = timespecToTime(stat.Mtimespec)
+	t.Ctime, t.HasCtime = timespecToTime(stat.Ctimespec), true
+	t.Btime, t.HasBtime = timespecToTime(stat.Birthtimespec), true
 }
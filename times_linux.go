@@ -9,9 +9,11 @@ package times
 import (
 	"errors"
 	"os"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
@@ -23,23 +25,17 @@ const (
 	HasBirthTime  = false
 )
 
-type timespec struct {
-	atime
-	mtime
-	ctime
-	nobtime
-}
-
-type timespecBtime struct {
-	atime
-	mtime
-	ctime
-	btime
-}
+// The ops of the *os.PathErrors returned by statx(2) calls.
+const (
+	opStat  = "statx"
+	opLstat = "lstatx"
+	opFstat = "fstatx"
+)
 
 var (
 	supportsStatx int32 = 1
-	statxFunc           = unix.Statx
+	statxFunc           = statx
+	statxPool           = sync.Pool{New: func() interface{} { return new(unix.Statx_t) }}
 )
 
 func isStatXSupported() bool {
@@ -55,131 +51,192 @@ func isStatXUnsupported(err error) bool {
 	return false
 }
 
-// Stat returns the Timespec for the given filename.
-func Stat(name string) (Timespec, error) {
-	if isStatXSupported() {
-		ts, err := statX(name)
-		if err == nil {
-			return ts, nil
+// statxProbe finds out once whether the kernel has statx(2), for statxState to be
+// right before the first Stat.
+var statxProbe sync.Once
+
+func statxState() (statx, fallback bool) {
+	statxProbe.Do(func() {
+		if isStatXSupported() {
+			var stat unix.Statx_t
+			isStatXUnsupported(statxFunc(unix.AT_FDCWD, "/", 0, 0, &stat))
 		}
-		if !isStatXUnsupported(err) {
-			return nil, err
+	})
+	supported := isStatXSupported()
+	return supported, !supported
+}
+
+// statx is unix.Statx without the heap allocated copy of path.
+func statx(dirfd int, path string, flags int, mask int, stat *unix.Statx_t) error {
+	var buf [unix.PathMax]byte
+	if len(path) >= len(buf) {
+		return unix.Statx(dirfd, path, flags, mask, stat)
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] == 0 {
+			return unix.EINVAL
 		}
-		// Fallback.
 	}
-	return stat(name, os.Stat)
+	copy(buf[:], path)
+
+	_, _, e := unix.Syscall6(unix.SYS_STATX, uintptr(dirfd), uintptr(unsafe.Pointer(&buf[0])), uintptr(flags), uintptr(mask), uintptr(unsafe.Pointer(stat)), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
 }
 
-func statX(name string) (Timespec, error) {
-	// https://man7.org/linux/man-pages/man2/statx.2.html
-	var statx unix.Statx_t
-	err := statxFunc(unix.AT_FDCWD, name, unix.AT_STATX_SYNC_AS_STAT, unix.STATX_ATIME|unix.STATX_MTIME|unix.STATX_CTIME|unix.STATX_BTIME, &statx)
-	if err != nil {
-		return nil, err
+func (s *Statter) statxFlags() int {
+	flags := unix.AT_STATX_SYNC_AS_STAT
+	switch s.sync {
+	case ForceSync:
+		flags = unix.AT_STATX_FORCE_SYNC
+	case DontSync:
+		flags = unix.AT_STATX_DONT_SYNC
+	}
+	if s.noAutomount {
+		flags |= unix.AT_NO_AUTOMOUNT
+	}
+	return flags
+}
+
+func (s *Statter) statxMask() int {
+	fields := s.wants()
+	// the inode and the size identify the file and tell whether it changed, for
+	// Index, Manifest, StableStat...; statx(2) only promises what is asked for.
+	mask := unix.STATX_INO | unix.STATX_SIZE
+	if fields&FieldAtime != 0 {
+		mask |= unix.STATX_ATIME
+	}
+	if fields&FieldMtime != 0 {
+		mask |= unix.STATX_MTIME
+	}
+	if fields&FieldCtime != 0 {
+		mask |= unix.STATX_CTIME
+	}
+	if fields&FieldBtime != 0 {
+		mask |= unix.STATX_BTIME
+	}
+	return mask
+}
+
+func (s *Statter) statInto(name string, dst *Times) error {
+	if isStatXSupported() {
+		err := s.statXInto(unix.AT_FDCWD, name, 0, dst)
+		if err == nil || !isStatXUnsupported(err) {
+			return pathErr(opStat, name, err)
+		}
+		// Fallback.
 	}
-	return extractTimes(&statx), nil
+	if s.strict {
+		return pathErr(opStat, name, ErrStatxUnavailable)
+	}
+	return statInto(name, os.Stat, dst)
 }
 
-// Lstat returns the Timespec for the given filename, and does not follow Symlinks.
-func Lstat(name string) (Timespec, error) {
+func (s *Statter) lstatInto(name string, dst *Times) error {
 	if isStatXSupported() {
-		ts, err := lstatx(name)
-		if err == nil {
-			return ts, nil
+		err := s.statXInto(unix.AT_FDCWD, name, unix.AT_SYMLINK_NOFOLLOW, dst)
+		if err == nil || !isStatXUnsupported(err) {
+			return pathErr(opLstat, name, err)
 		}
-		if !isStatXUnsupported(err) {
-			return nil, err
+		// Fallback.
+	}
+	if s.strict {
+		return pathErr(opLstat, name, ErrStatxUnavailable)
+	}
+	return statInto(name, os.Lstat, dst)
+}
+
+func (s *Statter) statFileInto(file *os.File, dst *Times) error {
+	if isStatXSupported() {
+		err := s.statXFileInto(file, dst)
+		if err == nil || !isStatXUnsupported(err) {
+			return pathErr(opFstat, file.Name(), err)
 		}
 		// Fallback.
 	}
-	return stat(name, os.Lstat)
+	if s.strict {
+		return pathErr(opFstat, file.Name(), ErrStatxUnavailable)
+	}
+	return statFileInto(file, dst)
 }
 
-func lstatx(name string) (Timespec, error) {
+func (s *Statter) statXInto(dirfd int, name string, flags int, dst *Times) error {
 	// https://man7.org/linux/man-pages/man2/statx.2.html
-	var statX unix.Statx_t
-	err := statxFunc(unix.AT_FDCWD, name, unix.AT_STATX_SYNC_AS_STAT|unix.AT_SYMLINK_NOFOLLOW, unix.STATX_ATIME|unix.STATX_MTIME|unix.STATX_CTIME|unix.STATX_BTIME, &statX)
+	statx := statxPool.Get().(*unix.Statx_t)
+	defer statxPool.Put(statx)
+
+	err := statxFunc(dirfd, name, flags|s.statxFlags(), s.statxMask(), statx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return extractTimes(&statX), nil
+	extractTimes(statx, dst)
+	return nil
 }
 
-func statXFile(file *os.File) (Timespec, error) {
+func (s *Statter) statXFileInto(file *os.File, dst *Times) error {
 	sc, err := file.SyscallConn()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var statx unix.Statx_t
 	var statxErr error
 	err = sc.Control(func(fd uintptr) {
-		// https://man7.org/linux/man-pages/man2/statx.2.html
-		statxErr = statxFunc(int(fd), "", unix.AT_EMPTY_PATH|unix.AT_STATX_SYNC_AS_STAT, unix.STATX_ATIME|unix.STATX_MTIME|unix.STATX_CTIME|unix.STATX_BTIME, &statx)
+		statxErr = s.statXInto(int(fd), "", unix.AT_EMPTY_PATH, dst)
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	if statxErr != nil {
-		return nil, statxErr
-	}
-
-	return extractTimes(&statx), nil
+	return statxErr
 }
 
-// StatFile returns the Timespec for the given *os.File.
-func StatFile(file *os.File) (Timespec, error) {
-	if isStatXSupported() {
-		ts, err := statXFile(file)
-		if err == nil {
-			return ts, nil
-		}
-		if !isStatXUnsupported(err) {
-			return nil, err
-		}
-		// Fallback.
-	}
-	return statFile(file)
-}
-
-func statFile(file *os.File) (Timespec, error) {
+func statFileInto(file *os.File, dst *Times) error {
 	fi, err := file.Stat()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return getTimespec(fi), nil
+	dst.setFileInfo(fi, SourceStat)
+	return nil
 }
 
 func statxTimestampToTime(ts unix.StatxTimestamp) time.Time {
 	return time.Unix(ts.Sec, int64(ts.Nsec))
 }
 
-func extractTimes(statx *unix.Statx_t) Timespec {
+// extractTimes fills dst with the times statx reported in its mask,
+// times missing from the mask are left zero.
+func extractTimes(statx *unix.Statx_t, dst *Times) {
+	*dst = Times{Source: SourceStatx, StatxMask: statx.Mask}
+	if statx.Mask&unix.STATX_ATIME == unix.STATX_ATIME {
+		dst.Atime = statxTimestampToTime(statx.Atime)
+	}
+	if statx.Mask&unix.STATX_MTIME == unix.STATX_MTIME {
+		dst.Mtime = statxTimestampToTime(statx.Mtime)
+	}
+	if statx.Mask&unix.STATX_CTIME == unix.STATX_CTIME {
+		dst.Ctime = statxTimestampToTime(statx.Ctime)
+		dst.HasCtime = true
+	}
 	if statx.Mask&unix.STATX_BTIME == unix.STATX_BTIME {
-		var t timespecBtime
-		t.atime.v = statxTimestampToTime(statx.Atime)
-		t.mtime.v = statxTimestampToTime(statx.Mtime)
-		t.ctime.v = statxTimestampToTime(statx.Ctime)
-		t.btime.v = statxTimestampToTime(statx.Btime)
-		return t
+		dst.Btime = statxTimestampToTime(statx.Btime)
+		dst.HasBtime = true
+	}
+	if statx.Mask&unix.STATX_INO == unix.STATX_INO {
+		dst.id = fileID{dev: unix.Mkdev(statx.Dev_major, statx.Dev_minor), ino: statx.Ino}
+	}
+	if statx.Mask&unix.STATX_SIZE == unix.STATX_SIZE {
+		dst.size, dst.hasSize = int64(statx.Size), true
 	}
-
-	var t timespec
-	t.atime.v = statxTimestampToTime(statx.Atime)
-	t.mtime.v = statxTimestampToTime(statx.Mtime)
-	t.ctime.v = statxTimestampToTime(statx.Ctime)
-	return t
 }
 
 func timespecToTime(ts syscall.Timespec) time.Time {
 	return time.Unix(int64(ts.Sec), int64(ts.Nsec))
 }
 
-func getTimespec(fi os.FileInfo) (t timespec) {
+func (t *Times) setSys(fi os.FileInfo) {
 	stat := fi.Sys().(*syscall.Stat_t)
-	t.atime.v = timespecToTime(stat.Atim)
-	t.mtime.v = timespecToTime(stat.Mtim)
-	t.ctime.v = timespecToTime(stat.Ctim)
-	return t
+	t.Atime = timespecToTime(stat.Atim)
+	t.Mtime = timespecToTime(stat.Mtim)
+	t.Ctime, t.HasCtime = timespecToTime(stat.Ctim), true
 }
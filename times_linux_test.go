@@ -2,7 +2,10 @@ package times
 
 import (
 	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -21,12 +24,13 @@ func statxT(t time.Time, hasBtime bool) *unix.Statx_t {
 
 	statxt := timeToStatx(t)
 
+	statx.Mask = unix.STATX_ATIME | unix.STATX_MTIME | unix.STATX_CTIME
 	statx.Atime = statxt
 	statx.Mtime = statxt
 	statx.Ctime = statxt
 
 	if hasBtime {
-		statx.Mask = unix.STATX_BTIME
+		statx.Mask |= unix.STATX_BTIME
 		statx.Btime = statxt
 	}
 
@@ -56,7 +60,10 @@ func setStatx(fn statxFuncTyp) func() {
 	atomic.StoreInt32(&supportsStatx, 1)
 	restoreStatx := statxFunc
 	statxFunc = fn
-	return func() { statxFunc = restoreStatx }
+	return func() {
+		statxFunc = restoreStatx
+		atomic.StoreInt32(&supportsStatx, 1)
+	}
 }
 
 func TestStatx(t *testing.T) {
@@ -79,7 +86,7 @@ func TestStatx(t *testing.T) {
 				fileAndDirTest(t, func(name string) {
 					ts, err := Stat(name)
 					if err != nil {
-						if err == test.wantErr {
+						if errors.Is(err, test.wantErr) {
 							return
 						}
 						t.Fatal(err.Error())
@@ -101,7 +108,7 @@ func TestStatx(t *testing.T) {
 
 					ts, err := StatFile(fi)
 					if err != nil {
-						if err == test.wantErr {
+						if errors.Is(err, test.wantErr) {
 							return
 						}
 						t.Fatal(err.Error())
@@ -117,7 +124,7 @@ func TestStatx(t *testing.T) {
 				fileAndDirTest(t, func(name string) {
 					ts, err := Lstat(name)
 					if err != nil {
-						if err == test.wantErr {
+						if errors.Is(err, test.wantErr) {
 							return
 						}
 						t.Fatal(err.Error())
@@ -128,3 +135,454 @@ func TestStatx(t *testing.T) {
 		})
 	}
 }
+
+func TestStatxBirthTimeOK(t *testing.T) {
+	now := time.Now()
+	for _, hasBtime := range []bool{true, false} {
+		restore := setStatx(fakeSupportedStatx(statxT(now, hasBtime)))
+
+		fileAndDirTest(t, func(name string) {
+			ts, err := Stat(name)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+
+			if _, ok := ChangeTimeOK(ts); !ok {
+				t.Error("expected ChangeTimeOK() to be true")
+			}
+
+			btime, ok := BirthTimeOK(ts)
+			if ok != hasBtime {
+				t.Errorf("BirthTimeOK() = %v, want %v", ok, hasBtime)
+			}
+			if ok && btime.Unix() != now.Unix() {
+				t.Errorf("BirthTimeOK() = %v, want %v", btime, now)
+			}
+		})
+
+		restore()
+	}
+}
+
+func TestStatIntoAllocs(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		var ts Times
+		if StatInto(f.Name(), &ts); !isStatXSupported() {
+			t.Skip("statx is not supported, the os.Stat fallback allocates")
+		}
+
+		tests := []struct {
+			name string
+			fn   func()
+		}{
+			{name: "StatInto", fn: func() { StatInto(f.Name(), &ts) }},
+			{name: "LstatInto", fn: func() { LstatInto(f.Name(), &ts) }},
+			{name: "StatFileInto", fn: func() { StatFileInto(f, &ts) }},
+		}
+		for _, test := range tests {
+			if allocs := testing.AllocsPerRun(100, test.fn); allocs != 0 {
+				t.Errorf("%s: got %v allocs, want 0", test.name, allocs)
+			}
+		}
+	})
+}
+
+func TestStatxNulByte(t *testing.T) {
+	var st unix.Statx_t
+	if err := statx(unix.AT_FDCWD, "bad\x00name", 0, DefaultStatter.statxMask(), &st); err != unix.EINVAL {
+		t.Errorf("expected EINVAL, got %v", err)
+	}
+}
+
+type statxCall struct {
+	dirfd int
+	path  string
+	flags int
+	mask  int
+}
+
+func recordStatx(ts *unix.Statx_t, calls *[]statxCall) statxFuncTyp {
+	return func(dirfd int, path string, flags int, mask int, stat *unix.Statx_t) (err error) {
+		*calls = append(*calls, statxCall{dirfd: dirfd, path: path, flags: flags, mask: mask})
+		*stat = *ts
+		stat.Mask &= uint32(mask)
+		return nil
+	}
+}
+
+func TestStatterStatxArgs(t *testing.T) {
+	const allMask = unix.STATX_ATIME | unix.STATX_MTIME | unix.STATX_CTIME | unix.STATX_BTIME
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantFlags int
+		wantMask  int
+	}{
+		{name: "default", wantFlags: unix.AT_STATX_SYNC_AS_STAT, wantMask: allMask},
+		{name: "force sync", opts: []Option{WithSync(ForceSync)}, wantFlags: unix.AT_STATX_FORCE_SYNC, wantMask: allMask},
+		{name: "dont sync", opts: []Option{WithSync(DontSync)}, wantFlags: unix.AT_STATX_DONT_SYNC, wantMask: allMask},
+		{name: "no automount", opts: []Option{WithNoAutomount()}, wantFlags: unix.AT_NO_AUTOMOUNT, wantMask: allMask},
+		{name: "mtime only", opts: []Option{WithFields(FieldMtime)}, wantMask: unix.STATX_MTIME},
+		{name: "atime and btime", opts: []Option{WithFields(FieldAtime | FieldBtime)}, wantMask: unix.STATX_ATIME | unix.STATX_BTIME},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []statxCall
+			restore := setStatx(recordStatx(statxT(time.Now(), true), &calls))
+			defer restore()
+
+			s := NewStatter(test.opts...)
+			fileTest(t, func(f *os.File) {
+				if _, err := s.Stat(f.Name()); err != nil {
+					t.Fatal(err.Error())
+				}
+				if _, err := s.Lstat(f.Name()); err != nil {
+					t.Fatal(err.Error())
+				}
+				if _, err := s.StatFile(f); err != nil {
+					t.Fatal(err.Error())
+				}
+			})
+
+			wantFlags := []int{test.wantFlags, test.wantFlags | unix.AT_SYMLINK_NOFOLLOW, test.wantFlags | unix.AT_EMPTY_PATH}
+			if len(calls) != len(wantFlags) {
+				t.Fatalf("got %d statx calls, want %d", len(calls), len(wantFlags))
+			}
+			for i, call := range calls {
+				if call.flags != wantFlags[i] {
+					t.Errorf("call %d: flags = %#x, want %#x", i, call.flags, wantFlags[i])
+				}
+				// the inode and the size are always asked for.
+				if want := test.wantMask | unix.STATX_INO | unix.STATX_SIZE; call.mask != want {
+					t.Errorf("call %d: mask = %#x, want %#x", i, call.mask, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStatterFieldsMask(t *testing.T) {
+	now := time.Now()
+	var calls []statxCall
+	restore := setStatx(recordStatx(statxT(now, true), &calls))
+	defer restore()
+
+	fileTest(t, func(f *os.File) {
+		ts, err := NewStatter(WithFields(FieldMtime)).Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if ts.ModTime().Unix() != now.Unix() {
+			t.Errorf("ModTime() = %v, want %v", ts.ModTime(), now)
+		}
+		if !ts.AccessTime().IsZero() {
+			t.Errorf("AccessTime() = %v, want zero time", ts.AccessTime())
+		}
+		if ts.HasChangeTime() {
+			t.Error("expected HasChangeTime() to be false")
+		}
+		if ts.HasBirthTime() {
+			t.Error("expected HasBirthTime() to be false")
+		}
+	})
+}
+
+func TestStatxProvenance(t *testing.T) {
+	tests := []struct {
+		name         string
+		statx        statxFuncTyp
+		wantSource   Source
+		wantBtime    bool
+		wantFallback bool
+	}{
+		{name: "unsupported", statx: unsupportedStatx, wantSource: SourceStat, wantFallback: true},
+		{name: "with btime", statx: fakeSupportedStatx(statxT(time.Now(), true)), wantSource: SourceStatx, wantBtime: true},
+		{name: "without btime", statx: fakeSupportedStatx(statxT(time.Now(), false)), wantSource: SourceStatx},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restore := setStatx(test.statx)
+			defer restore()
+
+			fileAndDirTest(t, func(name string) {
+				ts, err := Stat(name)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				times := ts.(Times)
+
+				if times.Source != test.wantSource {
+					t.Errorf("Source = %s, want %s", times.Source, test.wantSource)
+				}
+				if hasBtime := times.StatxMask&unix.STATX_BTIME != 0; hasBtime != test.wantBtime {
+					t.Errorf("StatxMask = %#x, want STATX_BTIME = %v", times.StatxMask, test.wantBtime)
+				}
+				if times.Source != SourceStatx && times.StatxMask != 0 {
+					t.Errorf("StatxMask = %#x, want 0 for source %s", times.StatxMask, times.Source)
+				}
+
+				f := SupportedFeatures()
+				if f.StatxFallback != test.wantFallback || f.Statx == test.wantFallback {
+					t.Errorf("SupportedFeatures() = %+v, want fallback = %v", f, test.wantFallback)
+				}
+			})
+		})
+	}
+}
+
+func TestSupportedFeaturesProbe(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		statx := unsupportedStatx
+		if supported {
+			statx = fakeSupportedStatx(statxT(time.Now(), true))
+		}
+		restore := setStatx(statx)
+		statxProbe = sync.Once{}
+
+		// before any Stat.
+		if f := SupportedFeatures(); f.Statx != supported || f.StatxFallback == supported {
+			t.Errorf("SupportedFeatures() = %+v, want statx = %v", f, supported)
+		}
+		restore()
+	}
+}
+
+func TestStatxStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		statx   statxFuncTyp
+		opts    []Option
+		wantErr error
+	}{
+		{name: "unsupported", statx: unsupportedStatx, wantErr: ErrStatxUnavailable},
+		{name: "with btime", statx: fakeSupportedStatx(statxT(time.Now(), true))},
+		{name: "without btime", statx: fakeSupportedStatx(statxT(time.Now(), false)), wantErr: ErrBirthTimeUnavailable},
+		{name: "without btime, btime not wanted", statx: fakeSupportedStatx(statxT(time.Now(), false)), opts: []Option{WithFields(FieldMtime | FieldCtime)}},
+		{name: "bad stat", statx: badStatx, wantErr: errBadStatx},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewStatter(append(test.opts, WithStrict())...)
+
+			check := func(t *testing.T, ts Timespec, err error) {
+				if test.wantErr != nil {
+					if !errors.Is(err, test.wantErr) {
+						t.Fatalf("got err %v, want %v", err, test.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				timespecTest(ts, newInterval(time.Now(), time.Second), t)
+			}
+
+			fileAndDirTest(t, func(name string) {
+				restore := setStatx(test.statx)
+				defer restore()
+				ts, err := s.Stat(name)
+				check(t, ts, err)
+
+				restore = setStatx(test.statx)
+				ts, err = s.Lstat(name)
+				check(t, ts, err)
+
+				restore = setStatx(test.statx)
+				f, err := os.Open(name)
+				if err != nil {
+					t.Fatal(err.Error())
+				}
+				defer f.Close()
+				ts, err = s.StatFile(f)
+				check(t, ts, err)
+			})
+		})
+	}
+}
+
+func TestStatxStrictAfterFallback(t *testing.T) {
+	restore := setStatx(unsupportedStatx)
+	defer restore()
+
+	fileTest(t, func(f *os.File) {
+		// the first non-strict call switches to the stat(2) fallback.
+		if _, err := Stat(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, err := DefaultStatter.With(WithStrict()).Stat(f.Name()); !errors.Is(err, ErrStatxUnavailable) {
+			t.Errorf("got err %v, want %v", err, ErrStatxUnavailable)
+		}
+	})
+}
+
+func TestStatxStrictMissingTimes(t *testing.T) {
+	statx := statxT(time.Now(), true)
+	statx.Mask = unix.STATX_MTIME
+	restore := setStatx(fakeSupportedStatx(statx))
+	defer restore()
+
+	fileTest(t, func(f *os.File) {
+		_, err := NewStatter(WithStrict()).Stat(f.Name())
+		var uerr *UnavailableError
+		if !errors.As(err, &uerr) {
+			t.Fatalf("got err %v, want *UnavailableError", err)
+		}
+		if want := FieldAtime | FieldCtime | FieldBtime; uerr.Missing != want {
+			t.Errorf("Missing = %s, want %s", uerr.Missing, want)
+		}
+		if !errors.Is(err, ErrChangeTimeUnavailable) || !errors.Is(err, ErrBirthTimeUnavailable) {
+			t.Errorf("expected %v to match the ctime and btime errors", err)
+		}
+	})
+}
+
+func errnoStatx(errno unix.Errno) statxFuncTyp {
+	return func(dirfd int, path string, flags int, mask int, stat *unix.Statx_t) (err error) {
+		return errno
+	}
+}
+
+func TestStatxPathError(t *testing.T) {
+	tests := []struct {
+		name    string
+		statx   statxFuncTyp
+		wantErr error
+	}{
+		{name: "not exist", statx: errnoStatx(unix.ENOENT), wantErr: fs.ErrNotExist},
+		{name: "permission", statx: errnoStatx(unix.EACCES), wantErr: fs.ErrPermission},
+		{name: "bad stat", statx: badStatx, wantErr: errBadStatx},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restore := setStatx(test.statx)
+			defer restore()
+
+			fileTest(t, func(f *os.File) {
+				_, err := Stat(f.Name())
+				checkPathError(t, err, "statx", f.Name(), test.wantErr)
+
+				_, err = Lstat(f.Name())
+				checkPathError(t, err, "lstatx", f.Name(), test.wantErr)
+
+				_, err = StatFile(f)
+				checkPathError(t, err, "fstatx", f.Name(), test.wantErr)
+			})
+		})
+	}
+}
+
+func TestStatxPathErrorStrict(t *testing.T) {
+	restore := setStatx(unsupportedStatx)
+	defer restore()
+
+	fileTest(t, func(f *os.File) {
+		_, err := NewStatter(WithStrict()).Lstat(f.Name())
+		checkPathError(t, err, "lstatx", f.Name(), ErrStatxUnavailable)
+	})
+
+	restore = setStatx(fakeSupportedStatx(statxT(time.Now(), false)))
+	fileTest(t, func(f *os.File) {
+		_, err := NewStatter(WithStrict()).StatFile(f)
+		checkPathError(t, err, "fstatx", f.Name(), ErrBirthTimeUnavailable)
+	})
+}
+
+func TestStatxFallbackPathError(t *testing.T) {
+	restore := setStatx(unsupportedStatx)
+	defer restore()
+
+	_, err := Stat("badfile?")
+	checkPathError(t, err, "stat", "badfile?", fs.ErrNotExist)
+
+	_, err = Lstat("badfile?")
+	checkPathError(t, err, "lstat", "badfile?", fs.ErrNotExist)
+}
+
+func TestStatxNotExistPathError(t *testing.T) {
+	restore := setStatx(statx)
+	defer restore()
+
+	_, err := Stat("badfile?")
+	checkPathError(t, err, "statx", "badfile?", fs.ErrNotExist)
+
+	_, err = Lstat("badfile?")
+	checkPathError(t, err, "lstatx", "badfile?", fs.ErrNotExist)
+}
+
+func TestStatAtStatx(t *testing.T) {
+	var calls []statxCall
+	restore := setStatx(recordStatx(statxT(time.Now(), true), &calls))
+	defer restore()
+
+	treeTest(t, func(dir *os.File) {
+		if _, err := StatAt(dir, "file", 0); err != nil {
+			t.Fatal(err.Error())
+		}
+		if _, err := StatAt(dir, "symlink", AtSymlinkNoFollow); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		want := []statxCall{
+			{dirfd: int(dir.Fd()), path: "file", flags: unix.AT_STATX_SYNC_AS_STAT},
+			{dirfd: int(dir.Fd()), path: "symlink", flags: unix.AT_STATX_SYNC_AS_STAT | unix.AT_SYMLINK_NOFOLLOW},
+		}
+		if len(calls) != len(want) {
+			t.Fatalf("got %d statx calls, want %d", len(calls), len(want))
+		}
+		for i, call := range calls {
+			call.mask = 0
+			if call != want[i] {
+				t.Errorf("call %d = %+v, want %+v", i, call, want[i])
+			}
+		}
+	})
+}
+
+func TestStatAtFallback(t *testing.T) {
+	restore := setStatx(unsupportedStatx)
+	defer restore()
+
+	treeTest(t, func(dir *os.File) {
+		ts, err := StatAt(dir, "symlink", AtSymlinkNoFollow)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+		if src := SourceOf(ts); src != SourceStat {
+			t.Errorf("SourceOf() = %s, want %s", src, SourceStat)
+		}
+
+		_, err = StatAt(dir, "missing", 0)
+		checkPathError(t, err, "fstatat", filepath.Join(dir.Name(), "missing"), fs.ErrNotExist)
+
+		_, err = NewStatter(WithStrict()).StatAt(dir, "file", 0)
+		checkPathError(t, err, "statx", filepath.Join(dir.Name(), "file"), ErrStatxUnavailable)
+	})
+}
+
+func TestStatFileID(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		ts, err := Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		id := idOf(ts)
+		if id == (fileID{}) {
+			t.Fatal("expected statx to fill the file id")
+		}
+
+		restore := setStatx(unsupportedStatx)
+		defer restore()
+		for _, stat := range []func(string) (Timespec, error){Stat, Lstat} {
+			ts, err := stat(f.Name())
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			if got := idOf(ts); got != id {
+				t.Errorf("got file id %v from the stat fallback, want %v", got, id)
+			}
+		}
+	})
+}
@@ -19,21 +19,13 @@ const (
 	HasBirthTime  = false
 )
 
-type timespec struct {
-	atime
-	mtime
-	ctime
-	nobtime
-}
-
 func timespecToTime(sec, nsec int64) time.Time {
 	return time.Unix(sec, nsec)
 }
 
-func getTimespec(fi os.FileInfo) (t timespec) {
+func (t *Times) setSys(fi os.FileInfo) {
 	stat := fi.Sys().(*syscall.Stat_t)
-	t.atime.v = timespecToTime(stat.Atime, stat.AtimeNsec)
-	t.mtime.v = timespecToTime(stat.Mtime, stat.MtimeNsec)
-	t.ctime.v = timespecToTime(stat.Ctime, stat.CtimeNsec)
-	return t
+	t.Atime = timespecToTime(stat.Atime, stat.AtimeNsec)
+	t.Mtime = timespecToTime(stat.Mtime, stat.MtimeNsec)
+	t.Ctime, t.HasCtime = timespecToTime(stat.Ctime, stat.CtimeNsec), true
 }
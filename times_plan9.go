@@ -19,16 +19,21 @@ const (
 	HasBirthTime  = false
 )
 
-type timespec struct {
-	atime
-	mtime
-	noctime
-	nobtime
+func hasPlatformSys(fi os.FileInfo) bool {
+	_, ok := fi.Sys().(*syscall.Dir)
+	return ok
 }
 
-func getTimespec(fi os.FileInfo) (t timespec) {
+func fileIDOf(fi os.FileInfo) fileID {
+	stat, ok := fi.Sys().(*syscall.Dir)
+	if !ok {
+		return fileID{}
+	}
+	return fileID{dev: uint64(stat.Dev), ino: stat.Qid.Path}
+}
+
+func (t *Times) setSys(fi os.FileInfo) {
 	stat := fi.Sys().(*syscall.Dir)
-	t.atime.v = time.Unix(int64(stat.Atime), 0)
-	t.mtime.v = time.Unix(int64(stat.Mtime), 0)
-	return t
+	t.Atime = time.Unix(int64(stat.Atime), 0)
+	t.Mtime = time.Unix(int64(stat.Mtime), 0)
 }
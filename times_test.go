@@ -1,6 +1,7 @@
 package times
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -144,3 +145,178 @@ func TestCheat(t *testing.T) {
 		t.Error("expected panic")
 	}
 }
+
+func TestTimeOK(t *testing.T) {
+	now := time.Now()
+
+	has := Times{Ctime: now, Btime: now, HasCtime: true, HasBtime: true}
+	if v, ok := ChangeTimeOK(has); !ok || !v.Equal(now) {
+		t.Errorf("ChangeTimeOK() = %v, %v", v, ok)
+	}
+	if v, ok := BirthTimeOK(has); !ok || !v.Equal(now) {
+		t.Errorf("BirthTimeOK() = %v, %v", v, ok)
+	}
+	if v, ok := ChangeTimeOK(Times{Ctime: now}); ok || !v.IsZero() {
+		t.Errorf("ChangeTimeOK() = %v, %v", v, ok)
+	}
+	if v, ok := BirthTimeOK(Times{Btime: now}); ok || !v.IsZero() {
+		t.Errorf("BirthTimeOK() = %v, %v", v, ok)
+	}
+}
+
+func TestTimeOKMatchesHas(t *testing.T) {
+	fileAndDirTest(t, func(name string) {
+		ts, err := Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if _, ok := ChangeTimeOK(ts); ok != ts.HasChangeTime() {
+			t.Errorf("ChangeTimeOK() = %v, HasChangeTime() = %v", ok, ts.HasChangeTime())
+		}
+		if _, ok := BirthTimeOK(ts); ok != ts.HasBirthTime() {
+			t.Errorf("BirthTimeOK() = %v, HasBirthTime() = %v", ok, ts.HasBirthTime())
+		}
+	})
+}
+
+func TestUnavailablePanic(t *testing.T) {
+	recoverString := func(f func()) (v string) {
+		defer func() {
+			v, _ = recover().(string)
+		}()
+		f()
+		return ""
+	}
+
+	if v := recoverString(func() { noctime{}.ChangeTime() }); v != "ctime not available" {
+		t.Errorf("got panic %q", v)
+	}
+	if v := recoverString(func() { nobtime{}.BirthTime() }); v != "birthtime not available" {
+		t.Errorf("got panic %q", v)
+	}
+}
+
+func TestStatInto(t *testing.T) {
+	fileAndDirTest(t, func(name string) {
+		var ts Times
+		if err := StatInto(name, &ts); err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+
+		want, err := Stat(name)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if !ts.Equal(want.(Times)) {
+			t.Errorf("StatInto() = %v, Stat() = %v", ts, want)
+		}
+	})
+}
+
+func TestLstatInto(t *testing.T) {
+	fileAndDirTest(t, func(name string) {
+		var ts Times
+		if err := LstatInto(name, &ts); err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+	})
+}
+
+func TestStatFileInto(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		var ts Times
+		if err := StatFileInto(f, &ts); err != nil {
+			t.Fatal(err.Error())
+		}
+		timespecTest(ts, newInterval(time.Now(), time.Second), t)
+	})
+}
+
+func TestStatIntoErr(t *testing.T) {
+	var ts Times
+	if err := StatInto("badfile?", &ts); err == nil {
+		t.Error("expected an error")
+	}
+	if err := LstatInto("badfile?", &ts); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestTimesEqual(t *testing.T) {
+	now := time.Now()
+	a := Times{Atime: now, Mtime: now, Ctime: now, HasCtime: true}
+	b := a
+	b.Atime = now.In(time.UTC)
+	if !a.Equal(b) {
+		t.Error("expected times in different locations to be equal")
+	}
+
+	b.Btime, b.HasBtime = now, true
+	if a.Equal(b) {
+		t.Error("expected times with different HasBtime to differ")
+	}
+
+	a.Ctime = now.Add(time.Second)
+	a.HasCtime = false
+	b = a
+	b.Ctime = now
+	if !a.Equal(b) {
+		t.Error("expected Ctime to be ignored when HasCtime is false")
+	}
+}
+
+func TestTimesUnavailable(t *testing.T) {
+	var ts Times
+	if _, ok := ChangeTimeOK(ts); ok {
+		t.Error("expected ChangeTimeOK() to be false")
+	}
+	if _, ok := BirthTimeOK(ts); ok {
+		t.Error("expected BirthTimeOK() to be false")
+	}
+
+	func() {
+		defer func() {
+			if v, _ := recover().(string); v != "ctime not available" {
+				t.Errorf("got panic %q", v)
+			}
+		}()
+		ts.ChangeTime()
+	}()
+
+	func() {
+		defer func() {
+			if v, _ := recover().(string); v != "birthtime not available" {
+				t.Errorf("got panic %q", v)
+			}
+		}()
+		ts.BirthTime()
+	}()
+}
+
+func checkPathError(t testing.TB, err error, op, path string, target error) {
+	t.Helper()
+
+	var perr *os.PathError
+	if !errors.As(err, &perr) {
+		t.Fatalf("got err %v (%T), want *os.PathError", err, err)
+	}
+	if (op != "" && perr.Op != op) || perr.Path != path {
+		t.Errorf("got op %q and path %q, want %q and %q", perr.Op, perr.Path, op, path)
+	}
+	if !errors.Is(err, target) {
+		t.Errorf("expected errors.Is(%v, %v)", err, target)
+	}
+}
+
+func TestStatPathError(t *testing.T) {
+	name := filepath.Join(os.TempDir(), "times-does-not-exist")
+
+	_, err := Stat(name)
+	checkPathError(t, err, "", name, os.ErrNotExist)
+
+	_, err = Lstat(name)
+	checkPathError(t, err, "", name, os.ErrNotExist)
+}
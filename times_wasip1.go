@@ -22,21 +22,13 @@ const (
 	HasBirthTime  = false
 )
 
-type timespec struct {
-	atime
-	mtime
-	ctime
-	nobtime
-}
-
 func timespecToTime(sec, nsec int64) time.Time {
 	return time.Unix(sec, nsec)
 }
 
-func getTimespec(fi os.FileInfo) (t timespec) {
+func (t *Times) setSys(fi os.FileInfo) {
 	stat := fi.Sys().(*syscall.Stat_t)
-	t.atime.v = timespecToTime(int64(stat.Atime), 0)
-	t.mtime.v = timespecToTime(int64(stat.Mtime), 0)
-	t.ctime.v = timespecToTime(int64(stat.Ctime), 0)
-	return t
+	t.Atime = timespecToTime(int64(stat.Atime), 0)
+	t.Mtime = timespecToTime(int64(stat.Mtime), 0)
+	t.Ctime, t.HasCtime = timespecToTime(int64(stat.Ctime), 0), true
 }
@@ -19,18 +19,20 @@ const (
 	HasBirthTime  = true
 )
 
-type timespec struct {
-	atime
-	mtime
-	noctime
-	btime
+func hasPlatformSys(fi os.FileInfo) bool {
+	_, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	return ok
 }
 
-func getTimespec(fi os.FileInfo) Timespec {
-	var t timespec
+// fileIDOf returns the zero fileID, the file index is not part of
+// the Win32FileAttributeData returned by os.Stat.
+func fileIDOf(fi os.FileInfo) fileID {
+	return fileID{}
+}
+
+func (t *Times) setSys(fi os.FileInfo) {
 	stat := fi.Sys().(*syscall.Win32FileAttributeData)
-	t.atime.v = time.Unix(0, stat.LastAccessTime.Nanoseconds())
-	t.mtime.v = time.Unix(0, stat.LastWriteTime.Nanoseconds())
-	t.btime.v = time.Unix(0, stat.CreationTime.Nanoseconds())
-	return t
+	t.Atime = time.Unix(0, stat.LastAccessTime.Nanoseconds())
+	t.Mtime = time.Unix(0, stat.LastWriteTime.Nanoseconds())
+	t.Btime, t.HasBtime = time.Unix(0, stat.CreationTime.Nanoseconds()), true
 }
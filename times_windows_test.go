@@ -21,7 +21,8 @@ func TestStatFileProcErr(t *testing.T) {
 }
 
 func TestStatBadNameErr(t *testing.T) {
-	_, err := platformSpecficStat(string([]byte{0}))
+	var ts Times
+	err := platformSpecficStat(string([]byte{0}), &ts)
 	if err != syscall.EINVAL {
 		t.Error(err)
 	}
@@ -52,3 +53,12 @@ func TestLstatProcErrFallback(t *testing.T) {
 		timespecTest(ts, newInterval(time.Now(), time.Second), t)
 	})
 }
+
+func TestStatFileIntoAllocs(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		var ts Times
+		if allocs := testing.AllocsPerRun(100, func() { StatFileInto(f, &ts) }); allocs != 0 {
+			t.Errorf("got %v allocs, want 0", allocs)
+		}
+	})
+}
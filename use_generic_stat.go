@@ -4,21 +4,28 @@ package times
 
 import "os"
 
-// Stat returns the Timespec for the given filename.
-func Stat(name string) (Timespec, error) {
-	return stat(name, os.Stat)
+// The ops of the *os.PathErrors returned by strict Statters.
+const (
+	opStat  = "stat"
+	opLstat = "lstat"
+	opFstat = "fstat"
+)
+
+func statxState() (statx, fallback bool) { return false, false }
+
+func (s *Statter) statInto(name string, dst *Times) error {
+	return statInto(name, os.Stat, dst)
 }
 
-// Lstat returns the Timespec for the given filename, and does not follow Symlinks.
-func Lstat(name string) (Timespec, error) {
-	return stat(name, os.Lstat)
+func (s *Statter) lstatInto(name string, dst *Times) error {
+	return statInto(name, os.Lstat, dst)
 }
 
-// StatFile returns the Timespec for the given *os.File.
-func StatFile(file *os.File) (Timespec, error) {
+func (s *Statter) statFileInto(file *os.File, dst *Times) error {
 	fi, err := file.Stat()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return getTimespec(fi), nil
+	dst.setFileInfo(fi, SourceStat)
+	return nil
 }
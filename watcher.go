@@ -0,0 +1,424 @@
+package times
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrWatcherClosed is returned by the methods of a closed Watcher.
+var ErrWatcherClosed = errors.New("watcher closed")
+
+// watcherErrorsBuffer is the size of the buffer of the Errors channels.
+const watcherErrorsBuffer = 64
+
+// Event is a change to the times of a watched path.
+type Event struct {
+	Path string
+
+	// Old and New are the times of Path before and after the change, Old is nil
+	// if Path was created and New is nil if it was removed.
+	Old, New Timespec
+
+	// Changed are the times which differ, all the times Old or New has if
+	// Path was created or removed.
+	Changed Field
+}
+
+// WatcherOptions configures a Watcher.
+type WatcherOptions struct {
+	// Interval is the time between polls, zero means 1s.
+	Interval time.Duration
+
+	// NoFollow uses Lstat instead of Stat, so symlinks are watched instead
+	// of their targets. Recursive watches never follow symlinks to directories.
+	NoFollow bool
+
+	// Workers is the number of goroutines calling Stat during a poll, zero means 4.
+	Workers int
+}
+
+// Watcher polls a set of paths for changes to their times. It works on every
+// filesystem which has times, including NFS and FUSE filesystems without inotify,
+// but only notices a change when it polls, and only the last of several changes
+// between two polls.
+type Watcher struct {
+	// Events receives the changes, it must be read for polling to go on.
+	// It is closed by Close.
+	Events <-chan Event
+
+	// Errors receives the errors of Stat, except for a path not existing
+	// (which is an Event). It is closed by Close. It need not be read, the errors
+	// are dropped once its buffer is full.
+	Errors <-chan error
+
+	events   chan Event
+	errors   chan error
+	interval time.Duration
+	nofollow bool
+	workers  int
+
+	mu      sync.Mutex
+	roots   map[string]bool // recursive or not
+	watched timesCache
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type watchedPath struct {
+	roots map[string]bool // the roots a path is watched by, they may overlap
+	t     Times
+}
+
+// timesCache holds the last times of the watched paths.
+type timesCache map[string]*watchedPath
+
+// watch records t, the times of path watched by root.
+func (c timesCache) watch(root, path string, t Times) {
+	wp, ok := c[path]
+	if !ok {
+		wp = &watchedPath{roots: make(map[string]bool)}
+		c[path] = wp
+	}
+	wp.roots[root] = true
+	wp.t = t
+}
+
+// unwatch stops root watching path, which is forgotten once no root watches it.
+func (c timesCache) unwatch(root, path string) {
+	if wp, ok := c[path]; ok {
+		delete(wp.roots, root)
+		if len(wp.roots) == 0 {
+			delete(c, path)
+		}
+	}
+}
+
+// update records t, the times of path watched by root, or err, the error getting them.
+// It returns the Event for the change, if there was one, or err if it is not an
+// error for path not existing. The times in ignore are not reported as changed.
+func (c timesCache) update(root, path string, t Times, err error, ignore Field) (Event, bool, error) {
+	old, ok := c[path]
+	switch {
+	case err == nil && ok:
+		prev := old.t
+		c.watch(root, path, t)
+		if changed := changedFields(prev, t) &^ ignore; changed != 0 {
+			return Event{Path: path, Old: prev, New: t, Changed: changed}, true, nil
+		}
+	case err == nil:
+		c.watch(root, path, t)
+		return Event{Path: path, New: t, Changed: t.fields()}, true, nil
+	case !os.IsNotExist(err):
+		return Event{}, false, err
+	case ok:
+		return c.remove(path), true, nil
+	}
+	return Event{}, false, nil
+}
+
+// remove forgets path and returns the Event for its removal.
+func (c timesCache) remove(path string) Event {
+	old := c[path]
+	delete(c, path)
+	return Event{Path: path, Old: old.t, Changed: old.t.fields()}
+}
+
+// NewWatcher returns a Watcher which polls with opts until it is closed.
+func NewWatcher(opts WatcherOptions) *Watcher {
+	w := &Watcher{
+		events:   make(chan Event),
+		errors:   make(chan error, watcherErrorsBuffer),
+		interval: opts.Interval,
+		nofollow: opts.NoFollow,
+		workers:  opts.Workers,
+		roots:    make(map[string]bool),
+		watched:  make(timesCache),
+		done:     make(chan struct{}),
+	}
+	if w.interval <= 0 {
+		w.interval = time.Second
+	}
+	if w.workers <= 0 {
+		w.workers = 4
+	}
+	w.Events, w.Errors = w.events, w.errors
+
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// Add watches path, which must exist. Changes are reported relative to its times now.
+// For a directory only the times of the directory itself are watched, see AddRecursive,
+// unless it was added with AddRecursive already.
+func (w *Watcher) Add(path string) error {
+	return w.add(path, false)
+}
+
+// AddRecursive watches the directory dir and every path beneath it,
+// paths created beneath it later are reported and watched too.
+// The directories are read on every poll, which changes their access times,
+// so those are not reported.
+func (w *Watcher) AddRecursive(dir string) error {
+	return w.add(dir, true)
+}
+
+func (w *Watcher) add(root string, recursive bool) error {
+	root = filepath.Clean(root)
+	if _, err := statPath(root, w.nofollow); err != nil {
+		return err
+	}
+	paths := []string{root}
+	if recursive {
+		var err error
+		if paths, _, err = walkPaths(root); err != nil {
+			return err
+		}
+	}
+	results := statPaths(paths, w.nofollow, w.workers)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWatcherClosed
+	}
+	w.roots[root] = w.roots[root] || recursive
+	for i, path := range paths {
+		if results[i].err == nil {
+			w.watched.watch(root, path, results[i].t)
+		}
+	}
+	return nil
+}
+
+// Remove stops watching path, it must have been added with Add or AddRecursive.
+func (w *Watcher) Remove(path string) error {
+	path = filepath.Clean(path)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWatcherClosed
+	}
+	if _, ok := w.roots[path]; !ok {
+		return &os.PathError{Op: "remove watch", Path: path, Err: errors.New("not watched")}
+	}
+	delete(w.roots, path)
+	for p := range w.watched {
+		w.watched.unwatch(path, p)
+	}
+	return nil
+}
+
+// Close stops polling and closes Events and Errors.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.done)
+	w.wg.Wait()
+	close(w.events)
+	close(w.errors)
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-t.C:
+		}
+		if !w.poll() {
+			return
+		}
+	}
+}
+
+// poll stats every watched path once and sends the changes,
+// it returns false if the Watcher was closed meanwhile.
+func (w *Watcher) poll() bool {
+	w.mu.Lock()
+	roots := make(map[string]bool, len(w.roots))
+	for root, recursive := range w.roots {
+		roots[root] = recursive
+	}
+	w.mu.Unlock()
+
+	var paths, pathRoots []string
+	var errs []error
+	// the directories read by the walks, whose access times were changed by the
+	// Watcher itself.
+	read := make(map[string]bool)
+	for root, recursive := range roots {
+		walked := []string{root}
+		if recursive {
+			var dirs []string
+			var err error
+			if walked, dirs, err = walkPaths(root); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			if len(walked) == 0 {
+				// gone, its Stat reports it.
+				walked = []string{root}
+			}
+			for _, dir := range dirs {
+				read[dir] = true
+			}
+		}
+		for _, path := range walked {
+			paths = append(paths, path)
+			pathRoots = append(pathRoots, root)
+		}
+	}
+	results := statPaths(paths, w.nofollow, w.workers)
+
+	var events []Event
+	w.mu.Lock()
+	seen := make(map[string]bool, len(paths))
+	for i, path := range paths {
+		root := pathRoots[i]
+		if _, ok := w.roots[root]; !ok {
+			// removed during the poll.
+			continue
+		}
+		seen[path] = true
+		var ignore Field
+		if read[path] {
+			ignore = FieldAtime
+		}
+		e, ok, err := w.watched.update(root, path, results[i].t, results[i].err, ignore)
+		if err != nil {
+			errs = append(errs, err)
+		} else if ok {
+			events = append(events, e)
+		}
+	}
+	for path, wp := range w.watched {
+		if !seen[path] && polled(wp.roots, roots) {
+			events = append(events, w.watched.remove(path))
+		}
+	}
+	w.mu.Unlock()
+
+	for _, e := range events {
+		select {
+		case w.events <- e:
+		case <-w.done:
+			return false
+		}
+	}
+	for _, err := range errs {
+		sendError(w.errors, err)
+	}
+	return true
+}
+
+// sendError sends err on errs unless its buffer is full, so a consumer which
+// only reads the events is not blocked.
+func sendError(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+	}
+}
+
+// polled reports whether any of the roots of a path were polled.
+func polled(roots, polled map[string]bool) bool {
+	for root := range roots {
+		if _, ok := polled[root]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// walkPaths returns root and every path beneath it, and the directories among them,
+// without following symlinks.
+func walkPaths(root string) (paths, dirs []string, err error) {
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path != root && os.IsNotExist(err) {
+				// removed while walking.
+				return nil
+			}
+			return err
+		}
+		paths = append(paths, path)
+		if d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return paths, dirs, err
+}
+
+type statResult struct {
+	t   Times
+	err error
+}
+
+// statPaths stats paths with at most workers goroutines.
+func statPaths(paths []string, nofollow bool, workers int) []statResult {
+	results := make([]statResult, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for n := 0; n < workers && n < len(paths); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i].t, results[i].err = statPath(paths[i], nofollow)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func statPath(path string, nofollow bool) (t Times, err error) {
+	if nofollow {
+		err = LstatInto(path, &t)
+	} else {
+		err = StatInto(path, &t)
+	}
+	return t, err
+}
+
+// changedFields returns the times which differ between a and b.
+func changedFields(a, b Times) Field {
+	var f Field
+	if !a.Atime.Equal(b.Atime) {
+		f |= FieldAtime
+	}
+	if !a.Mtime.Equal(b.Mtime) {
+		f |= FieldMtime
+	}
+	if a.HasCtime != b.HasCtime || !a.Ctime.Equal(b.Ctime) {
+		f |= FieldCtime
+	}
+	if a.HasBtime != b.HasBtime || !a.Btime.Equal(b.Btime) {
+		f |= FieldBtime
+	}
+	return f
+}
@@ -0,0 +1,341 @@
+package times
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	fileTest(t, func(f *os.File) {
+		w := NewWatcher(WatcherOptions{Interval: 10 * time.Millisecond, Workers: 2})
+		defer w.Close()
+
+		old, err := Stat(f.Name())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := w.Add(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		at := time.Now().Add(-time.Hour).Truncate(time.Second)
+		if err := os.Chtimes(f.Name(), at, at); err != nil {
+			t.Fatal(err.Error())
+		}
+		e := nextEvent(t, w)
+		if e.Path != filepath.Clean(f.Name()) || e.Old == nil || e.New == nil {
+			t.Fatalf("unexpected event %+v", e)
+		}
+		if want := FieldAtime | FieldMtime; e.Changed&want != want || e.Changed&FieldBtime != 0 {
+			t.Errorf("got changed %s, want %s", e.Changed, want)
+		}
+		if e.Old.(Times).fields()&FieldCtime != 0 && e.Changed&FieldCtime == 0 {
+			t.Error("expected the change time to change")
+		}
+		timespecTest(e.Old, newInterval(old.ModTime(), 0), t, Timespec.ModTime)
+		timespecTest(e.New, newInterval(at, 0), t, Timespec.AccessTime, Timespec.ModTime)
+
+		if err := w.Remove(f.Name()); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := os.Chtimes(f.Name(), old.AccessTime(), old.ModTime()); err != nil {
+			t.Fatal(err.Error())
+		}
+		expectNoEvent(t, w)
+	})
+}
+
+func TestWatcherRecursive(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, path := range []string{sub, dir} {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	w := NewWatcher(WatcherOptions{Interval: 10 * time.Millisecond, NoFollow: true})
+	defer w.Close()
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	name := filepath.Join(sub, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	events := collectEvents(t, w, 2)
+	created, ok := events[name]
+	if !ok || created.Old != nil || created.New == nil || created.Changed&(FieldAtime|FieldMtime) == 0 {
+		t.Errorf("unexpected created event %+v", created)
+	}
+	if e, ok := events[sub]; !ok || e.Changed&FieldMtime == 0 {
+		t.Errorf("expected the mtime of %s to change, got %+v", sub, e)
+	}
+
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err.Error())
+	}
+	events = collectEvents(t, w, 2)
+	removed, ok := events[name]
+	if !ok || removed.Old == nil || removed.New != nil {
+		t.Errorf("unexpected removed event %+v", removed)
+	}
+}
+
+func TestWatcherRecursiveIdle(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(sub, "file"), nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the Watcher reads the directories, which must not be reported.
+	w := NewWatcher(WatcherOptions{Interval: 10 * time.Millisecond, NoFollow: true})
+	defer w.Close()
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectNoEvent(t, w)
+
+	name := filepath.Join(sub, "new")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	events := collectEvents(t, w, 2)
+	if _, ok := events[name]; !ok {
+		t.Errorf("expected an event for %s, got %+v", name, events)
+	}
+	if e, ok := events[sub]; !ok || e.Changed&FieldAtime != 0 {
+		t.Errorf("expected the times of %s to change without its atime, got %+v", sub, e)
+	}
+	expectNoEvent(t, w)
+}
+
+func TestWatcherOverlapping(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w := NewWatcher(WatcherOptions{Interval: 10 * time.Millisecond, NoFollow: true})
+	defer w.Close()
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := w.Add(name); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// still watched by dir.
+	if err := w.Remove(name); err != nil {
+		t.Fatal(err.Error())
+	}
+	expectNoEvent(t, w)
+	at := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, at, at); err != nil {
+		t.Fatal(err.Error())
+	}
+	if e := nextEvent(t, w); e.Path != name || e.Old == nil || e.New == nil {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestWatcherAddRecursiveTwice(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWatcher(WatcherOptions{Interval: 10 * time.Millisecond})
+	defer w.Close()
+	if err := w.AddRecursive(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+	// still recursive.
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	name := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	for {
+		if e := nextEvent(t, w); e.Path == name {
+			break
+		}
+	}
+}
+
+func TestWatcherRemoved(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w := NewWatcher(WatcherOptions{Interval: 10 * time.Millisecond})
+	defer w.Close()
+	if err := w.Add(name); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err.Error())
+	}
+	if e := nextEvent(t, w); e.Path != name || e.Old == nil || e.New != nil {
+		t.Errorf("unexpected removed event %+v", e)
+	}
+
+	// still watched, it comes back.
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	if e := nextEvent(t, w); e.Path != name || e.Old != nil || e.New == nil {
+		t.Errorf("unexpected created event %+v", e)
+	}
+}
+
+func TestWatcherErr(t *testing.T) {
+	w := NewWatcher(WatcherOptions{})
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := w.Add(missing); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+	if err := w.AddRecursive(missing); !os.IsNotExist(err) {
+		t.Errorf("got err %v, want not exist", err)
+	}
+	if err := w.Remove(missing); err == nil {
+		t.Error("expected an error removing a path which isn't watched")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, ok := <-w.Events; ok {
+		t.Error("expected Events to be closed")
+	}
+	if _, ok := <-w.Errors; ok {
+		t.Error("expected Errors to be closed")
+	}
+	if err := w.Add(t.TempDir()); !errors.Is(err, ErrWatcherClosed) {
+		t.Errorf("got err %v, want %v", err, ErrWatcherClosed)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("got err %v closing twice", err)
+	}
+}
+
+func TestWatcherErrorsNotRead(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err.Error())
+	}
+	name := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w := NewWatcher(WatcherOptions{Interval: time.Millisecond})
+	defer w.Close()
+	for _, path := range []string{filepath.Join(sub, "x"), name} {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := w.Add(path); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	// sub/x fails with ENOTDIR on every poll, and Errors is not read.
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := ioutil.WriteFile(sub, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	at := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(name, at, at); err != nil {
+		t.Fatal(err.Error())
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-w.Events:
+			if e.Path == name {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for an event")
+		}
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	now := time.Now()
+	a := Times{Atime: now, Mtime: now, Ctime: now, Btime: now, HasCtime: true, HasBtime: true}
+	tests := []struct {
+		name string
+		b    func(b *Times)
+		want Field
+	}{
+		{"same", func(b *Times) { b.Source = SourceStat }, 0},
+		{"atime", func(b *Times) { b.Atime = now.Add(1) }, FieldAtime},
+		{"mtime", func(b *Times) { b.Mtime = now.Add(1) }, FieldMtime},
+		{"ctime", func(b *Times) { b.Ctime = now.Add(1) }, FieldCtime},
+		{"btime", func(b *Times) { b.Btime = now.Add(1) }, FieldBtime},
+		{"no btime", func(b *Times) { b.HasBtime = false }, FieldBtime},
+		{"all", func(b *Times) { *b = Times{} }, AllFields},
+	}
+	for _, test := range tests {
+		b := a
+		test.b(&b)
+		if got := changedFields(a, b); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func nextEvent(t *testing.T, w *Watcher) Event {
+	t.Helper()
+	select {
+	case e := <-w.Events:
+		return e
+	case err := <-w.Errors:
+		t.Fatal(err.Error())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return Event{}
+}
+
+// collectEvents returns the events of the next n distinct paths.
+func collectEvents(t *testing.T, w *Watcher, n int) map[string]Event {
+	t.Helper()
+	events := make(map[string]Event)
+	for len(events) < n {
+		e := nextEvent(t, w)
+		events[e.Path] = e
+	}
+	return events
+}
+
+func expectNoEvent(t *testing.T, w *Watcher) {
+	t.Helper()
+	select {
+	case e := <-w.Events:
+		t.Errorf("unexpected event %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}